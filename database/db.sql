@@ -40,6 +40,7 @@ CREATE TABLE `asset` (
                        `id` bigint(20) NOT NULL AUTO_INCREMENT,
                        `uid` bigint(20) NOT NULL,
                        `account` int(10) unsigned NOT NULL,
+                       `dst` int(10) unsigned NOT NULL DEFAULT '0' COMMENT 'Beneficiary of the hold (0 for none)',
                        `amount` decimal(7,3) NOT NULL DEFAULT '0.000',
                        PRIMARY KEY (`id`),
                        UNIQUE KEY `work_index` (`account`,`uid`) USING BTREE,
@@ -61,14 +62,100 @@ CREATE TABLE `history` (
                          `account` int(10) unsigned NOT NULL,
                          `amount` decimal(7,3) NOT NULL DEFAULT '0.000',
                          `op` tinyint(4) NOT NULL COMMENT 'Operation code',
+                         `peer` varchar(64) NOT NULL DEFAULT '' COMMENT 'Billing instance, that sent the transfer',
                          `registered` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
                          PRIMARY KEY (`id`),
-                         UNIQUE KEY `work_index` (`account`,`uid`,`op`),
+                         UNIQUE KEY `work_index` (`account`,`uid`,`op`,`peer`),
                          KEY `account_index` (`account`),
                          CONSTRAINT `log_fk1` FOREIGN KEY (`account`) REFERENCES `account` (`id`) ON DELETE CASCADE
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
 /*!40101 SET character_set_client = @saved_cs_client */;
 
+--
+-- Table structure for table `saga`
+--
+
+DROP TABLE IF EXISTS `saga`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+SET character_set_client = utf8mb4 ;
+CREATE TABLE `saga` (
+                      `id` bigint(20) NOT NULL AUTO_INCREMENT,
+                      `uid` bigint(20) NOT NULL,
+                      `src` int(10) unsigned NOT NULL,
+                      `dst` int(10) unsigned NOT NULL,
+                      `amount` decimal(7,3) NOT NULL DEFAULT '0.000',
+                      `state` tinyint(4) NOT NULL COMMENT 'State of the transfer',
+                      `updated` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+                      PRIMARY KEY (`id`),
+                      UNIQUE KEY `work_index` (`src`,`uid`),
+                      KEY `state_index` (`state`),
+                      CONSTRAINT `saga_fk1` FOREIGN KEY (`src`) REFERENCES `account` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `escrow`
+--
+
+DROP TABLE IF EXISTS `escrow`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+SET character_set_client = utf8mb4 ;
+CREATE TABLE `escrow` (
+                        `id` bigint(20) NOT NULL COMMENT 'Escrow id of the client',
+                        `account` int(10) unsigned NOT NULL COMMENT 'Account of the buyer',
+                        `amount` decimal(7,3) NOT NULL DEFAULT '0.000' COMMENT 'Initial amount',
+                        `balance` decimal(7,3) NOT NULL DEFAULT '0.000' COMMENT 'Amount, that is not released or refunded',
+                        `created` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                        PRIMARY KEY (`id`,`account`),
+                        KEY `account_index` (`account`),
+                        CONSTRAINT `escrow_fk1` FOREIGN KEY (`account`) REFERENCES `account` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `escrow_log`
+--
+
+DROP TABLE IF EXISTS `escrow_log`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+SET character_set_client = utf8mb4 ;
+CREATE TABLE `escrow_log` (
+                            `id` bigint(20) NOT NULL AUTO_INCREMENT,
+                            `escrow` bigint(20) NOT NULL,
+                            `buyer` int(10) unsigned NOT NULL COMMENT 'Account of the buyer (owner of the escrow)',
+                            `uid` bigint(20) NOT NULL,
+                            `account` int(10) unsigned NOT NULL COMMENT 'Buyer or seller',
+                            `amount` decimal(7,3) NOT NULL DEFAULT '0.000',
+                            `op` tinyint(4) NOT NULL COMMENT 'Operation code',
+                            `registered` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                            PRIMARY KEY (`id`),
+                            UNIQUE KEY `work_index` (`escrow`,`buyer`,`uid`,`account`),
+                            CONSTRAINT `escrow_log_fk1` FOREIGN KEY (`escrow`,`buyer`) REFERENCES `escrow` (`id`,`account`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `reversal`
+--
+
+DROP TABLE IF EXISTS `reversal`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+SET character_set_client = utf8mb4 ;
+CREATE TABLE `reversal` (
+                          `id` bigint(20) NOT NULL AUTO_INCREMENT,
+                          `uid` bigint(20) NOT NULL,
+                          `original` bigint(20) NOT NULL COMMENT 'Uid of the reversed operation',
+                          `account` int(10) unsigned NOT NULL COMMENT 'Account of the original operation',
+                          `dst` int(10) unsigned NOT NULL DEFAULT '0' COMMENT 'Recipient of the reversed transfer (0 for none)',
+                          `amount` decimal(7,3) NOT NULL DEFAULT '0.000',
+                          `registered` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                          PRIMARY KEY (`id`),
+                          UNIQUE KEY `work_index` (`account`,`uid`),
+                          KEY `original_index` (`account`,`original`),
+                          CONSTRAINT `reversal_fk1` FOREIGN KEY (`account`) REFERENCES `account` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
 --
 -- Dumping routines for database 'billing'
 --
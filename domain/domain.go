@@ -1,8 +1,11 @@
 package domain
 
 import (
+	"context"
 	"errors"
+	"github.com/adverax/echo/database/sql"
 	"github.com/go-sql-driver/mysql"
+	"time"
 )
 
 const (
@@ -13,6 +16,13 @@ const (
 	OperationAcquire
 	OperationCommit
 	OperationRollback
+	OperationTransferRefund // Return of the amount of the failed cross-shard transfer to the source
+	OperationCommitDst      // Receipt of the amount of the committed hold by its beneficiary
+	OperationEscrowOpen     // Move of the amount of the buyer into the escrow
+	OperationEscrowRelease  // Receipt of the amount of the escrow by the seller
+	OperationEscrowRefund   // Return of the amount of the escrow to the buyer
+	OperationReverse        // Compensation of the original operation of the account
+	OperationReverseDst     // Return of the amount of the reversed transfer by its recipient
 )
 
 const (
@@ -21,12 +31,114 @@ const (
 	StatusDeprecated
 	StatusNoMoney
 	StatusNotFound
+	StatusInvalidRequest
+	StatusTimeout
+)
+
+// State of the cross-shard transfer
+const (
+	SagaReserved    SagaState = iota + 1 // Amount is withdrawn from the source
+	SagaCompleted                        // Amount is deposited to the destination
+	SagaCompensated                      // Amount is returned to the source
 )
 
 type Operation uint8
 
+type SagaState uint8
+
+// Tables of the database schema (see database/db.sql)
+var SchemaTables = []string{"account", "asset", "history", "saga", "escrow", "escrow_log", "reversal"}
+
+// Registered operation of the account
+type HistoryRecord struct {
+	Id         int64
+	Uid        int64
+	Account    uint32
+	Amount     float32
+	Op         Operation
+	Peer       string // Billing instance, that sent the transfer ("" for local operations)
+	Registered time.Time
+}
+
+// Source of the connections for the read-only queries, that tolerate replication lag.
+// Queries, that lock rows or belong to the transaction, must use the primary.
+type Reader interface {
+	Reader(ctx context.Context) sql.Scope
+}
+
+// Transfer between accounts of the different shards.
+// Saga is stored in the shard of the source account.
+type Saga struct {
+	Uid     int64
+	Src     uint32
+	Dst     uint32
+	Amount  float32
+	State   SagaState
+	Updated time.Time
+}
+
+// Funds of the buyer, that are kept until release to the sellers or refund
+type Escrow struct {
+	Id      int64
+	Account uint32  // Account of the buyer
+	Amount  float32 // Initial amount
+	Balance float32 // Amount, that is not released or refunded yet
+	Created time.Time
+}
+
+// Registered movement of the escrow (audit trail)
+type EscrowRecord struct {
+	Uid        int64
+	Account    uint32 // Account of the buyer (open, refund) or the seller (release)
+	Amount     float32
+	Op         Operation
+	Peer       string // Billing instance, that sent the transfer ("" for local operations)
+	Registered time.Time
+}
+
+// Part of the escrow, that is released to the seller
+type Payout struct {
+	Account uint32
+	Amount  float32
+}
+
+// Reversal of the completed operation (Credit, Debit or Transfer)
+type Reversal struct {
+	Uid      int64
+	Original int64  // Uid of the reversed operation
+	Account  uint32 // Account of the original operation (source of the transfer)
+	Dst      uint32 // Recipient of the reversed transfer (0 for Credit and Debit)
+	Amount   float32
+}
+
 var ErrNoMoney = errors.New("no money")
+var ErrOriginalNotFound = errors.New("original operation is not found")
+var ErrReversalExceeded = errors.New("reversal exceeds the rest of the original operation")
+var ErrShardNotFound = errors.New("account is not mapped to a shard")
 var ErrOperationIsDeprecated = errors.New("operation is deprecated")
+var ErrOverloaded = errors.New("service is overloaded")
+
+// ValidationError is machine-readable reason of request rejection.
+type ValidationError string
+
+func (err ValidationError) Error() string {
+	return string(err)
+}
+
+const (
+	ErrUidRequired        ValidationError = "uid_required"
+	ErrAccountRequired    ValidationError = "account_required"
+	ErrAmountNotFinite    ValidationError = "amount_not_finite"
+	ErrAmountNotPositive  ValidationError = "amount_not_positive"
+	ErrSameAccount        ValidationError = "same_account"
+	ErrMalformedRequest   ValidationError = "malformed_request"
+	ErrForeignBeneficiary ValidationError = "foreign_beneficiary" // Beneficiary is in the other shard
+	ErrEscrowRequired     ValidationError = "escrow_required"
+	ErrPayoutsRequired    ValidationError = "payouts_required"
+	ErrDuplicatePayout    ValidationError = "duplicate_payout"
+	ErrOriginalRequired   ValidationError = "original_required"
+	ErrPeerRequired       ValidationError = "peer_required"
+)
 
 func IsDuplicateKeyError(err error) bool {
 	if mysqlError, ok := err.(*mysql.MySQLError); ok {
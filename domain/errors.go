@@ -0,0 +1,245 @@
+package domain
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"github.com/adverax/echo/data"
+	"github.com/adverax/echo/database/sql"
+	"github.com/go-sql-driver/mysql"
+)
+
+// Error codes are part of public API. Never change existing codes.
+const (
+	ErrorCodeUnknown     = "unknown"
+	ErrorCodeDeprecated  = "deprecated"
+	ErrorCodeNoMoney     = "no_money"
+	ErrorCodeNotFound    = "not_found"
+	ErrorCodeDeadlock    = "deadlock"
+	ErrorCodeLockTimeout = "lock_timeout"
+	ErrorCodeUnavailable = "unavailable"
+	ErrorCodeOverloaded  = "overloaded"
+	ErrorCodeTimeout     = "timeout"
+	ErrorCodeCancelled   = "cancelled"
+
+	ErrorCodeOriginalNotFound = "original_not_found"
+	ErrorCodeReversalExceeded = "reversal_exceeded"
+)
+
+const (
+	mysqlLockWaitTimeout = 1205
+	mysqlDeadlock        = 1213
+	mysqlNoReferencedRow = 1452
+)
+
+// Public description of the error
+type ErrorInfo struct {
+	Status    uint8  // Status of the operation
+	Code      string // Stable machine-readable code
+	Message   string // Human-readable message
+	Retryable bool   // Operation can be repeated with the same uid
+}
+
+var (
+	errorUnknown = ErrorInfo{
+		Status:  StatusUnknownError,
+		Code:    ErrorCodeUnknown,
+		Message: "internal error",
+	}
+	errorNotFound = ErrorInfo{
+		Status:  StatusNotFound,
+		Code:    ErrorCodeNotFound,
+		Message: "account or hold is not found",
+	}
+	errorUnavailable = ErrorInfo{
+		Status:    StatusUnknownError,
+		Code:      ErrorCodeUnavailable,
+		Message:   "database is unavailable",
+		Retryable: true,
+	}
+)
+
+// Known error with its public description
+type knownError struct {
+	err  error
+	info ErrorInfo
+}
+
+// Known errors are matched by errors.Is in this order, so wrapped errors are described too
+var errorCatalog = []knownError{
+	{ErrNoMoney, ErrorInfo{
+		Status:  StatusNoMoney,
+		Code:    ErrorCodeNoMoney,
+		Message: "insufficient funds",
+	}},
+	{ErrOriginalNotFound, ErrorInfo{
+		Status:  StatusNotFound,
+		Code:    ErrorCodeOriginalNotFound,
+		Message: "original operation is not found or cannot be reversed",
+	}},
+	{ErrReversalExceeded, ErrorInfo{
+		Status:  StatusNoMoney,
+		Code:    ErrorCodeReversalExceeded,
+		Message: "amount exceeds the rest of the original operation",
+	}},
+	{ErrOperationIsDeprecated, ErrorInfo{
+		Status:  StatusDeprecated,
+		Code:    ErrorCodeDeprecated,
+		Message: "operation with the same uid is already processed",
+	}},
+	{ErrOverloaded, ErrorInfo{
+		Status:    StatusUnknownError,
+		Code:      ErrorCodeOverloaded,
+		Message:   "too many requests in progress",
+		Retryable: true,
+	}},
+	{context.DeadlineExceeded, ErrorInfo{
+		Status:    StatusTimeout,
+		Code:      ErrorCodeTimeout,
+		Message:   "operation timed out",
+		Retryable: true,
+	}},
+	{context.Canceled, ErrorInfo{
+		Status:    StatusUnknownError,
+		Code:      ErrorCodeCancelled,
+		Message:   "operation is cancelled",
+		Retryable: true,
+	}},
+	{ErrShardNotFound, errorNotFound},
+	{sql.ErrNoRows, errorNotFound},
+	{data.ErrNoMatch, errorNotFound},
+	{driver.ErrBadConn, errorUnavailable},
+	{mysql.ErrInvalidConn, errorUnavailable},
+	{ErrUidRequired, ErrorInfo{
+		Status:  StatusInvalidRequest,
+		Code:    string(ErrUidRequired),
+		Message: "uid is required",
+	}},
+	{ErrAccountRequired, ErrorInfo{
+		Status:  StatusInvalidRequest,
+		Code:    string(ErrAccountRequired),
+		Message: "account is required",
+	}},
+	{ErrAmountNotFinite, ErrorInfo{
+		Status:  StatusInvalidRequest,
+		Code:    string(ErrAmountNotFinite),
+		Message: "amount must be a finite number",
+	}},
+	{ErrAmountNotPositive, ErrorInfo{
+		Status:  StatusInvalidRequest,
+		Code:    string(ErrAmountNotPositive),
+		Message: "amount must be greater than zero",
+	}},
+	{ErrSameAccount, ErrorInfo{
+		Status:  StatusInvalidRequest,
+		Code:    string(ErrSameAccount),
+		Message: "source and destination accounts must differ",
+	}},
+	{ErrForeignBeneficiary, ErrorInfo{
+		Status:  StatusInvalidRequest,
+		Code:    string(ErrForeignBeneficiary),
+		Message: "beneficiary must be in the shard of the paying account",
+	}},
+	{ErrEscrowRequired, ErrorInfo{
+		Status:  StatusInvalidRequest,
+		Code:    string(ErrEscrowRequired),
+		Message: "escrow is required",
+	}},
+	{ErrPayoutsRequired, ErrorInfo{
+		Status:  StatusInvalidRequest,
+		Code:    string(ErrPayoutsRequired),
+		Message: "at least one payout is required",
+	}},
+	{ErrDuplicatePayout, ErrorInfo{
+		Status:  StatusInvalidRequest,
+		Code:    string(ErrDuplicatePayout),
+		Message: "seller must be paid once per operation",
+	}},
+	{ErrOriginalRequired, ErrorInfo{
+		Status:  StatusInvalidRequest,
+		Code:    string(ErrOriginalRequired),
+		Message: "uid of the original operation is required",
+	}},
+	{ErrPeerRequired, ErrorInfo{
+		Status:  StatusInvalidRequest,
+		Code:    string(ErrPeerRequired),
+		Message: "name of the sending instance is required",
+	}},
+	{ErrMalformedRequest, ErrorInfo{
+		Status:  StatusInvalidRequest,
+		Code:    string(ErrMalformedRequest),
+		Message: "request cannot be decoded",
+	}},
+}
+
+// Failure of the operation, that is reported by other billing instance
+type RemoteError struct {
+	Code      string
+	Message   string
+	Retryable bool
+}
+
+func (err *RemoteError) Error() string {
+	return err.Code + ": " + err.Message
+}
+
+// Describe error for the client.
+// Second result is false, when the error is unexpected and must be logged.
+func DescribeError(err error) (ErrorInfo, bool) {
+	for _, known := range errorCatalog {
+		if errors.Is(err, known.err) {
+			return known.info, true
+		}
+	}
+
+	var mysqlError *mysql.MySQLError
+	if errors.As(err, &mysqlError) {
+		switch mysqlError.Number {
+		case mysqlNoReferencedRow:
+			// Record refers to the missing account (e.g. history of the destination of the transfer)
+			return errorNotFound, true
+		case mysqlDeadlock:
+			return ErrorInfo{
+				Status:    StatusUnknownError,
+				Code:      ErrorCodeDeadlock,
+				Message:   "transaction is aborted by deadlock",
+				Retryable: true,
+			}, false
+		case mysqlLockWaitTimeout:
+			return ErrorInfo{
+				Status:    StatusUnknownError,
+				Code:      ErrorCodeLockTimeout,
+				Message:   "lock wait timeout exceeded",
+				Retryable: true,
+			}, false
+		}
+	}
+
+	var e *RemoteError
+	if errors.As(err, &e) {
+		info := ErrorInfo{
+			Status:    StatusUnknownError,
+			Code:      e.Code,
+			Message:   e.Message,
+			Retryable: e.Retryable,
+		}
+		for _, known := range errorCatalog {
+			if known.info.Code == e.Code {
+				info.Status = known.info.Status
+				break
+			}
+		}
+		return info, e.Code != ErrorCodeUnknown
+	}
+
+	var validation ValidationError
+	if errors.As(err, &validation) {
+		return ErrorInfo{
+			Status:  StatusInvalidRequest,
+			Code:    string(validation),
+			Message: string(validation),
+		}, true
+	}
+
+	return errorUnknown, false
+}
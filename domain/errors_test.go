@@ -0,0 +1,114 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/adverax/echo/database/sql"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// Error, that can not be compared by ==
+type detailsError []string
+
+func (err detailsError) Error() string {
+	return fmt.Sprint([]string(err))
+}
+
+func TestDescribeError(t *testing.T) {
+	type Dst struct {
+		status    uint8
+		code      string
+		retryable bool
+		known     bool
+	}
+
+	type Test struct {
+		src error
+		dst Dst
+	}
+
+	tests := map[string]Test{
+		"No money must be mapped": {
+			src: ErrNoMoney,
+			dst: Dst{status: StatusNoMoney, code: ErrorCodeNoMoney, known: true},
+		},
+		"Deprecated operation must be mapped": {
+			src: ErrOperationIsDeprecated,
+			dst: Dst{status: StatusDeprecated, code: ErrorCodeDeprecated, known: true},
+		},
+		"Missing row must be mapped": {
+			src: sql.ErrNoRows,
+			dst: Dst{status: StatusNotFound, code: ErrorCodeNotFound, known: true},
+		},
+		"Missing referenced account must be mapped": {
+			src: &mysql.MySQLError{Number: 1452},
+			dst: Dst{status: StatusNotFound, code: ErrorCodeNotFound, known: true},
+		},
+		"Remote error must keep its code": {
+			src: &RemoteError{Code: ErrorCodeNotFound, Message: "account or hold is not found"},
+			dst: Dst{status: StatusNotFound, code: ErrorCodeNotFound, known: true},
+		},
+		"Unknown remote error must be logged": {
+			src: &RemoteError{Code: ErrorCodeUnknown, Message: "internal error"},
+			dst: Dst{status: StatusUnknownError, code: ErrorCodeUnknown},
+		},
+		"Validation error must be mapped": {
+			src: ErrSameAccount,
+			dst: Dst{status: StatusInvalidRequest, code: "same_account", known: true},
+		},
+		"Overload must be retryable": {
+			src: ErrOverloaded,
+			dst: Dst{status: StatusUnknownError, code: ErrorCodeOverloaded, retryable: true, known: true},
+		},
+		"Timeout must be mapped": {
+			src: context.DeadlineExceeded,
+			dst: Dst{status: StatusTimeout, code: ErrorCodeTimeout, retryable: true, known: true},
+		},
+		"Deadlock must be retryable": {
+			src: &mysql.MySQLError{Number: 1213},
+			dst: Dst{status: StatusUnknownError, code: ErrorCodeDeadlock, retryable: true},
+		},
+		"Lock wait timeout must be retryable": {
+			src: &mysql.MySQLError{Number: 1205},
+			dst: Dst{status: StatusUnknownError, code: ErrorCodeLockTimeout, retryable: true},
+		},
+		"Wrapped timeout must be mapped": {
+			src: fmt.Errorf("query: %w", context.DeadlineExceeded),
+			dst: Dst{status: StatusTimeout, code: ErrorCodeTimeout, retryable: true, known: true},
+		},
+		"Wrapped missing row must be mapped": {
+			src: fmt.Errorf("account 1: %w", sql.ErrNoRows),
+			dst: Dst{status: StatusNotFound, code: ErrorCodeNotFound, known: true},
+		},
+		"Wrapped deadlock must be retryable": {
+			src: fmt.Errorf("commit: %w", &mysql.MySQLError{Number: 1213}),
+			dst: Dst{status: StatusUnknownError, code: ErrorCodeDeadlock, retryable: true},
+		},
+		"Wrapped remote error must keep its code": {
+			src: fmt.Errorf("peer: %w", &RemoteError{Code: ErrorCodeNoMoney, Message: "insufficient funds"}),
+			dst: Dst{status: StatusNoMoney, code: ErrorCodeNoMoney, known: true},
+		},
+		"Incomparable error must be hidden": {
+			src: detailsError{"secret", "details"},
+			dst: Dst{status: StatusUnknownError, code: ErrorCodeUnknown},
+		},
+		"Unknown error must be hidden": {
+			src: errors.New("secret details"),
+			dst: Dst{status: StatusUnknownError, code: ErrorCodeUnknown},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			info, known := DescribeError(test.src)
+			assert.Equal(t, test.dst.status, info.Status)
+			assert.Equal(t, test.dst.code, info.Code)
+			assert.Equal(t, test.dst.retryable, info.Retryable)
+			assert.Equal(t, test.dst.known, known)
+			assert.NotEqual(t, "", info.Message)
+		})
+	}
+}
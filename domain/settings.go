@@ -0,0 +1,277 @@
+package domain
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Option of the configuration, that can be overridden by the environment variable or flag.
+// Lists are written as comma-separated values (a,b), maps as comma-separated pairs (a=1,b=2).
+type setting struct {
+	key     string // Path of the option (e.g. database.node.0.password)
+	get     func() string
+	set     func(value string) error
+	virtual bool // Option of the element, that is added on set (next to the last one or with the known name)
+}
+
+// Get name of the environment variable of the option
+func (s setting) Env() string {
+	return envPrefix + strings.ToUpper(strings.NewReplacer(".", "_").Replace(s.key))
+}
+
+// Known names of the entries of the maps of the structures (by path of the map).
+// Options of these entries can be set, even if the entry is not described in the file.
+var settingNames = map[string][]string{
+	"broker.endpoint": EndpointNames,
+}
+
+// Adapter of the option to flag.Value
+type settingValue struct {
+	setting
+}
+
+func (v *settingValue) String() string {
+	if v.get == nil {
+		return ""
+	}
+	return v.get()
+}
+
+func (v *settingValue) Set(value string) error {
+	return v.set(value)
+}
+
+// Collect options of the fields of the structure, that are mapped to the config file.
+// Lists of the structures (e.g. database.node) can be extended by the element next to the last one,
+// maps of the structures (e.g. broker.endpoint) - by the entry with the known name.
+func collectSettings(prefix string, v reflect.Value) []setting {
+	var settings []setting
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("toml"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		settings = append(settings, valueSettings(prefix+name, v.Field(i))...)
+	}
+	return settings
+}
+
+func valueSettings(key string, v reflect.Value) []setting {
+	t := v.Type()
+	switch {
+	case isPlainType(t):
+		return []setting{{
+			key: key,
+			get: func() string { return formatValue(v) },
+			set: func(value string) error { return parseValue(v, value) },
+		}}
+
+	case t.Kind() == reflect.Struct:
+		return collectSettings(key+".", v)
+
+	case t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct:
+		if v.IsNil() {
+			return nil
+		}
+		return collectSettings(key+".", v.Elem())
+
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Ptr && t.Elem().Elem().Kind() == reflect.Struct:
+		var settings []setting
+		for i := 0; i < v.Len(); i++ {
+			settings = append(settings, valueSettings(key+"."+strconv.Itoa(i), v.Index(i))...)
+		}
+
+		item := reflect.New(t.Elem().Elem())
+		appended := false
+		for _, s := range collectSettings(key+"."+strconv.Itoa(v.Len())+".", item.Elem()) {
+			set := s.set
+			s.virtual = true
+			s.set = func(value string) error {
+				if !appended {
+					v.Set(reflect.Append(v, item))
+					appended = true
+				}
+				return set(value)
+			}
+			settings = append(settings, s)
+		}
+		return settings
+
+	case t.Kind() == reflect.Map && t.Key().Kind() == reflect.String && t.Elem().Kind() == reflect.Struct:
+		// Values of the map are not addressable, so they are modified by copy
+		var settings []setting
+		for _, name := range sortedKeys(v) {
+			name := reflect.ValueOf(name).Convert(t.Key())
+			item := reflect.New(t.Elem())
+			item.Elem().Set(v.MapIndex(name))
+			for _, s := range collectSettings(key+"."+name.String()+".", item.Elem()) {
+				set := s.set
+				s.set = func(value string) error {
+					err := set(value)
+					if err == nil {
+						v.SetMapIndex(name, item.Elem())
+					}
+					return err
+				}
+				settings = append(settings, s)
+			}
+		}
+
+		for _, name := range settingNames[key] {
+			name := reflect.ValueOf(name).Convert(t.Key())
+			if v.MapIndex(name).IsValid() {
+				continue
+			}
+			item := reflect.New(t.Elem())
+			for _, s := range collectSettings(key+"."+name.String()+".", item.Elem()) {
+				set := s.set
+				s.virtual = true
+				s.set = func(value string) error {
+					err := set(value)
+					if err == nil {
+						if v.IsNil() {
+							v.Set(reflect.MakeMap(t))
+						}
+						v.SetMapIndex(name, item.Elem())
+					}
+					return err
+				}
+				settings = append(settings, s)
+			}
+		}
+		return settings
+	}
+
+	return nil
+}
+
+// Check, that the value of the type is written as single string
+func isPlainType(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Slice:
+		return t.Elem().Kind() == reflect.String
+	case reflect.Map:
+		return t.Key().Kind() == reflect.String && isPlainType(t.Elem()) && t.Elem().Kind() != reflect.Slice
+	}
+	return false
+}
+
+func parseValue(v reflect.Value, value string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		items := reflect.MakeSlice(v.Type(), 0, 0)
+		for _, item := range splitList(value) {
+			items = reflect.Append(items, reflect.ValueOf(item).Convert(v.Type().Elem()))
+		}
+		v.Set(items)
+	case reflect.Map:
+		items := reflect.MakeMap(v.Type())
+		for _, pair := range splitList(value) {
+			name, item, ok := strings.Cut(pair, "=")
+			if !ok {
+				return fmt.Errorf("pair %q must be written as key=value", pair)
+			}
+			elem := reflect.New(v.Type().Elem()).Elem()
+			err := parseValue(elem, strings.TrimSpace(item))
+			if err != nil {
+				return err
+			}
+			items.SetMapIndex(reflect.ValueOf(strings.TrimSpace(name)).Convert(v.Type().Key()), elem)
+		}
+		v.Set(items)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+func formatValue(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Slice:
+		items := make([]string, v.Len())
+		for i := range items {
+			items[i] = formatValue(v.Index(i))
+		}
+		return strings.Join(items, ",")
+	case reflect.Map:
+		var items []string
+		for _, name := range sortedKeys(v) {
+			items = append(items, name+"="+formatValue(v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))))
+		}
+		return strings.Join(items, ",")
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func sortedKeys(v reflect.Value) []string {
+	keys := make([]string, 0, v.Len())
+	for _, key := range v.MapKeys() {
+		keys = append(keys, key.String())
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Parse environment variables (KEY=VALUE)
+func parseEnviron(environ []string) map[string]string {
+	env := make(map[string]string, len(environ))
+	for _, pair := range environ {
+		if key, value, ok := strings.Cut(pair, "="); ok {
+			env[key] = value
+		}
+	}
+	return env
+}
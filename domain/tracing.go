@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"context"
+	"database/sql"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Name of the instrumentation of the service
+const TracerName = "billing"
+
+// Get tracer of the registered provider
+func Tracer() trace.Tracer {
+	return otel.Tracer(TracerName)
+}
+
+// Start span of the SQL statement
+func StartQuerySpan(
+	ctx context.Context,
+	name string,
+	query string,
+) (context.Context, trace.Span) {
+	return Tracer().Start(
+		ctx,
+		name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "mysql"),
+			attribute.String("db.statement", query),
+		),
+	)
+}
+
+// Finish span with the error of the operation.
+// Missing rows are the regular result of the query, so they are not recorded.
+func EndSpan(span trace.Span, err error) {
+	if err != nil && err != sql.ErrNoRows {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
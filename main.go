@@ -5,32 +5,174 @@ import (
 	"billing/manager/account"
 	"billing/manager/asset"
 	"billing/manager/banker"
+	"billing/manager/escrow"
 	"billing/manager/history"
+	"billing/manager/replica"
+	"billing/manager/reversal"
+	"billing/manager/saga"
+	"billing/manager/shard"
 	"billing/service"
 	"context"
-	"github.com/adverax/echo/log"
+	"flag"
+	"fmt"
+	"github.com/adverax/echo/database/sql"
+	"github.com/prometheus/client_golang/prometheus"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
 )
 
 func main() {
-	ctx := context.Background()
+	config, err := domain.LoadConfig(os.Args[1:], os.Environ())
+	if err == flag.ErrHelp {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	level := new(slog.LevelVar)
+	logger, err := service.NewLogger(os.Stderr, config.Log, level)
+	if err != nil {
+		panic(err)
+	}
 
-	dsc := domain.Config.Database.DSC()
-	db, err := dsc.Open(nil)
+	metrics, err := service.NewMetrics(prometheus.NewRegistry())
 	if err != nil {
 		panic(err)
 	}
-	defer db.Close(ctx)
+
+	health := service.NewHealth()
+
+	var shards []*shard.Shard
+	for _, database := range config.Sharding.Databases(config.Database) {
+		options := database.Database
+		suffix := ""
+		if database.Name != "" {
+			suffix = "." + database.Name
+		}
+
+		dsc := options.Primary()
+		db, err := dsc.Open(nil)
+		if err != nil {
+			panic(err)
+		}
+		defer db.Close(ctx)
+
+		var replicas []sql.DB
+		for _, dsc := range options.Replicas() {
+			rdb, err := dsc.Open(nil)
+			if err != nil {
+				panic(err)
+			}
+			defer rdb.Close(ctx)
+			replicas = append(replicas, rdb)
+		}
+		router := replica.NewRouter(
+			db,
+			replicas,
+			time.Duration(options.MaxLag)*time.Second,
+			logger.With("shard", database.Name),
+		)
+		if len(replicas) != 0 {
+			go router.Run(ctx, time.Duration(options.LagInterval)*time.Second)
+		}
+
+		if stater, ok := db.(service.DBStater); ok {
+			err = metrics.RegisterShard(database.Name, service.NewDBStatsCollector(stater))
+			if err != nil {
+				panic(err)
+			}
+		} else {
+			logger.Warn("Statistics of the database pool are not available", "shard", database.Name)
+		}
+
+		heartbeat := service.NewHeartbeat(
+			service.PingCheck(db),
+			time.Duration(options.Heartbeat)*time.Second,
+			logger.With("shard", database.Name),
+		)
+		if options.Heartbeat > 0 {
+			go heartbeat.Run(ctx)
+		}
+		health.Register("database"+suffix, heartbeat.Check)
+		health.Register("migrations"+suffix, service.SchemaCheck(db, domain.SchemaTables...))
+
+		repository := service.NewTracingRepository(
+			service.NewMetricsRepository(sql.NewRepository(db), metrics),
+		)
+		accounts := account.NewWithReader(db, router)
+		assets := asset.New(db)
+		records := history.NewWithReader(db, router)
+		s := &shard.Shard{
+			Name:       database.Name,
+			Min:        database.Min,
+			Max:        database.Max,
+			Repository: repository,
+			Banker:     banker.NewWithRepository(repository, accounts, assets, records, reversal.New(db)),
+			Escrow:     banker.NewEscrow(repository, accounts, records, escrow.New(db)),
+			Accounts:   accounts,
+			History:    records,
+			Assets:     assets,
+			Sagas:      saga.New(db),
+		}
+		shards = append(shards, s)
+	}
+
+	lookup := shard.RangeMap(shards)
+	if config.Sharding.Strategy == "hash" {
+		lookup = shard.HashMap(shards)
+	}
+	var peers []*shard.Peer
+	for _, options := range config.Sharding.Peers {
+		timeout := config.Sharding.PeerTimeout(options)
+		peers = append(peers, &shard.Peer{
+			Name:    options.Name,
+			Min:     options.Min,
+			Max:     options.Max,
+			Remote:  service.NewHttpPeer(options.Url, config.Sharding.Name, &http.Client{Timeout: timeout}),
+			Timeout: timeout,
+		})
+	}
+	manager := shard.New(
+		shards,
+		lookup,
+		peers,
+		config.Sharding.ResumeAge(),
+		logger,
+	)
+
+	err = metrics.Register(service.NewHoldsCollector(manager))
+	if err != nil {
+		panic(err)
+	}
+
+	err = manager.Resume(ctx)
+	if err != nil {
+		logger.Error("Transfers are not resumed", "error", err)
+	}
+	go manager.Run(ctx, time.Duration(config.Sharding.ResumeInterval)*time.Second)
 
 	err = service.Bootstrap(
 		ctx,
-		banker.New(
-			db,
-			account.New(db),
-			asset.New(db),
-			history.New(db),
+		manager,
+		manager,
+		service.NewReloader(
+			config,
+			func() (domain.Configuration, error) {
+				return domain.LoadConfig(os.Args[1:], os.Environ())
+			},
+			level,
+			logger,
 		),
-		domain.Config.Broker,
-		log.NewDebug(""),
+		metrics,
+		health,
+		logger,
 	)
 	if err != nil {
 		panic(err)
@@ -9,10 +9,12 @@ import (
 type Manager interface {
 	Credit(ctx context.Context, account uint32, amount float32) error
 	Debit(ctx context.Context, account uint32, amount float32) error
+	Balance(ctx context.Context, account uint32) (amount float32, err error)
 }
 
 type engine struct {
 	sql.Repository
+	reader domain.Reader
 }
 
 func (engine *engine) Credit(
@@ -46,6 +48,17 @@ func (engine *engine) Debit(
 	)
 }
 
+func (engine *engine) Balance(
+	ctx context.Context,
+	account uint32,
+) (amount float32, err error) {
+	const query = "SELECT amount FROM account WHERE id = ?"
+	ctx, span := domain.StartQuerySpan(ctx, "account.balance", query)
+	err = engine.reader.Reader(ctx).QueryRowContext(ctx, query, account).Scan(&amount)
+	domain.EndSpan(span, err)
+	return
+}
+
 func (engine *engine) upgrade(
 	ctx context.Context,
 	account uint32,
@@ -58,7 +71,9 @@ func (engine *engine) upgrade(
 
 			const query1 = "SELECT amount FROM account WHERE id = ? FOR UPDATE"
 			var sum float32
-			err := scope.QueryRow(query1, account).Scan(&sum)
+			ctx1, span := domain.StartQuerySpan(ctx, "account.lock", query1)
+			err := scope.QueryRowContext(ctx1, query1, account).Scan(&sum)
+			domain.EndSpan(span, err)
 			if err != nil {
 				return err
 			}
@@ -69,7 +84,9 @@ func (engine *engine) upgrade(
 			}
 
 			const query2 = "UPDATE account SET amount = ? WHERE id = ?"
-			_, err = scope.Exec(query2, res, account)
+			ctx2, span := domain.StartQuerySpan(ctx, "account.update", query2)
+			_, err = scope.ExecContext(ctx2, query2, res, account)
+			domain.EndSpan(span, err)
 			return err
 		},
 	)
@@ -77,8 +94,30 @@ func (engine *engine) upgrade(
 
 func New(
 	db sql.DB,
+) Manager {
+	repository := sql.NewRepository(db)
+	return &engine{
+		Repository: repository,
+		reader:     primaryReader{repository},
+	}
+}
+
+// Create manager, that reads balances by the reader (e.g. from the replicas)
+func NewWithReader(
+	db sql.DB,
+	reader domain.Reader,
 ) Manager {
 	return &engine{
 		Repository: sql.NewRepository(db),
+		reader:     reader,
 	}
 }
+
+// Reader of the database of the repository
+type primaryReader struct {
+	sql.Repository
+}
+
+func (r primaryReader) Reader(ctx context.Context) sql.Scope {
+	return r.Scope(ctx)
+}
@@ -6,12 +6,17 @@ import (
 	"github.com/adverax/echo/database/sql"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"os"
 	"testing"
 )
 
 func setUp() (context.Context, sql.DB) {
 	ctx := context.Background()
-	return ctx, domain.Config.Database.DSC().OpenForTest(ctx)
+	config, err := domain.LoadConfig(nil, os.Environ())
+	if err != nil {
+		panic(err)
+	}
+	return ctx, config.Database.DSC().OpenForTest(ctx)
 }
 
 func TestEngine_Credit(t *testing.T) {
@@ -152,6 +157,52 @@ INSERT INTO account SET id = 1;`
 	}
 }
 
+func TestEngine_Balance(t *testing.T) {
+	type Dst struct {
+		amount float32
+		err    error
+	}
+
+	type Test struct {
+		account uint32
+		dst     Dst
+	}
+
+	tests := map[string]Test{
+		"Existing account must be found": {
+			account: 1,
+			dst: Dst{
+				amount: 100,
+			},
+		},
+		"Invalid account must be skipped": {
+			account: 2,
+			dst: Dst{
+				err: sql.ErrNoRows,
+			},
+		},
+	}
+
+	ctx, db := setUp()
+	defer db.Close(ctx)
+
+	e := New(db)
+
+	const query = `
+DELETE FROM account;
+INSERT INTO account SET id = 1, amount = 100;`
+	_, err := db.Exec(query)
+	require.NoError(t, err)
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			amount, err := e.Balance(ctx, test.account)
+			require.Equal(t, test.dst.err, err)
+			assert.Equal(t, test.dst.amount, amount)
+		})
+	}
+}
+
 func getAccount(db sql.DB, id uint32) (amount float32, err error) {
 	const query = "SELECT amount FROM account WHERE id = ?"
 	err = db.QueryRow(query, id).Scan(&amount)
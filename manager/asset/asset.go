@@ -11,54 +11,76 @@ type Manager interface {
 		ctx context.Context,
 		uid int64,
 		account uint32,
+		dst uint32,
 		amount float32,
 	) error
 	Remove(ctx context.Context,
 		uid int64,
 		account uint32,
-	) (amount float32, err error)
+	) (amount float32, dst uint32, err error)
+	Summary(ctx context.Context) (count int64, amount float32, err error)
 }
 
 type engine struct {
 	sql.Repository
 }
 
+// Hold amount of the account. Beneficiary dst receives the amount on commit (0 for none).
 func (engine *engine) Append(
 	ctx context.Context,
 	uid int64,
 	account uint32,
+	dst uint32,
 	amount float32,
 ) error {
-	const query = "INSERT INTO asset SET uid = ?, account = ?, amount = ?"
-	_, err := engine.Scope(ctx).Exec(query, uid, account, amount)
+	const query = "INSERT INTO asset SET uid = ?, account = ?, dst = ?, amount = ?"
+	ctx, span := domain.StartQuerySpan(ctx, "asset.append", query)
+	_, err := engine.Scope(ctx).ExecContext(ctx, query, uid, account, dst, amount)
+	domain.EndSpan(span, err)
 	return domain.HandleDeprecatedError(err)
 }
 
+// Remove hold and get its amount and beneficiary
 func (engine *engine) Remove(
 	ctx context.Context,
 	uid int64,
 	account uint32,
-) (amount float32, err error) {
+) (amount float32, dst uint32, err error) {
 	err = engine.Transaction(
 		ctx,
 		func(ctx context.Context) error {
 			scope := engine.Scope(ctx)
 
-			const query1 = "SELECT id, amount FROM asset WHERE uid = ? AND account = ? FOR UPDATE"
+			const query1 = "SELECT id, amount, dst FROM asset WHERE uid = ? AND account = ? FOR UPDATE"
 			var id int64
-			err := scope.QueryRow(query1, uid, account).Scan(&id, &amount)
+			ctx1, span := domain.StartQuerySpan(ctx, "asset.lock", query1)
+			err := scope.QueryRowContext(ctx1, query1, uid, account).Scan(&id, &amount, &dst)
+			domain.EndSpan(span, err)
 			if err != nil {
 				return err
 			}
 
 			const query2 = "DELETE FROM asset WHERE id = ?"
-			_, err = scope.Exec(query2, id)
+			ctx2, span := domain.StartQuerySpan(ctx, "asset.remove", query2)
+			_, err = scope.ExecContext(ctx2, query2, id)
+			domain.EndSpan(span, err)
 			return err
 		},
 	)
 	return
 }
 
+// Get count and total amount of the active holds
+func (engine *engine) Summary(
+	ctx context.Context,
+) (count int64, amount float32, err error) {
+	const query = "SELECT COUNT(*), COALESCE(SUM(amount), 0) FROM asset"
+	ctx, span := domain.StartQuerySpan(ctx, "asset.summary", query)
+	err = engine.Scope(ctx).QueryRowContext(ctx, query).Scan(&count, &amount)
+	domain.EndSpan(span, err)
+	return
+}
+
 func New(db sql.DB) Manager {
 	return &engine{
 		Repository: sql.NewRepository(db),
@@ -6,12 +6,17 @@ import (
 	"github.com/adverax/echo/database/sql"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"os"
 	"testing"
 )
 
 func setUp() (context.Context, sql.DB) {
 	ctx := context.Background()
-	return ctx, domain.Config.Database.DSC().OpenForTest(ctx)
+	config, err := domain.LoadConfig(nil, os.Environ())
+	if err != nil {
+		panic(err)
+	}
+	return ctx, config.Database.DSC().OpenForTest(ctx)
 }
 
 func TestEngine_Append(t *testing.T) {
@@ -19,6 +24,7 @@ func TestEngine_Append(t *testing.T) {
 		id      int64
 		uid     int64
 		account uint32
+		dst     uint32
 		amount  float32
 	}
 
@@ -55,6 +61,25 @@ func TestEngine_Append(t *testing.T) {
 				},
 			},
 		},
+		"Row with beneficiary must be accepted": {
+			src: Src{
+				Row: Row{
+					uid:     101,
+					account: 1,
+					dst:     2,
+					amount:  20,
+				},
+			},
+			dst: Dst{
+				Row: &Row{
+					id:      2,
+					uid:     101,
+					account: 1,
+					dst:     2,
+					amount:  20,
+				},
+			},
+		},
 		"Duplicated row must be rejected": {
 			src: Src{
 				Row: Row{
@@ -89,6 +114,7 @@ INSERT INTO asset SET uid = 1, account = 1, amount = 10;
 			err = e.Append(ctx,
 				test.src.uid,
 				test.src.account,
+				test.src.dst,
 				test.src.amount,
 			)
 			require.Equal(t, test.dst.err, err)
@@ -96,9 +122,9 @@ INSERT INTO asset SET uid = 1, account = 1, amount = 10;
 				return
 			}
 
-			const query2 = "SELECT uid, account, amount FROM asset WHERE id = ?"
+			const query2 = "SELECT uid, account, dst, amount FROM asset WHERE id = ?"
 			row := Row{id: test.dst.id}
-			err = db.QueryRow(query2, test.dst.id).Scan(&row.uid, &row.account, &row.amount)
+			err = db.QueryRow(query2, test.dst.id).Scan(&row.uid, &row.account, &row.dst, &row.amount)
 			require.NoError(t, err)
 			assert.Equal(t, test.dst.Row, &row)
 		})
@@ -112,9 +138,15 @@ func TestEngine_Remove(t *testing.T) {
 		account uint32
 	}
 
+	type Dst struct {
+		amount float32
+		dst    uint32
+		err    error
+	}
+
 	type Test struct {
 		src Src
-		dst error
+		dst Dst
 	}
 
 	tests := map[string]Test{
@@ -123,14 +155,22 @@ func TestEngine_Remove(t *testing.T) {
 				uid:     1,
 				account: 1,
 			},
-			dst: nil,
+			dst: Dst{amount: 10},
+		},
+		"Beneficiary of the row must be returned": {
+			src: Src{
+				init:    "INSERT INTO asset SET uid = 3, account = 1, dst = 2, amount = 15;",
+				uid:     3,
+				account: 1,
+			},
+			dst: Dst{amount: 15, dst: 2},
 		},
 		"Invalid row must throw error": {
 			src: Src{
 				uid:     2,
 				account: 1,
 			},
-			dst: sql.ErrNoRows,
+			dst: Dst{err: sql.ErrNoRows},
 		},
 	}
 
@@ -151,11 +191,62 @@ INSERT INTO asset SET uid = 1, account = 1, amount = 10;
 			_, err := db.Exec(query)
 			require.NoError(t, err)
 
-			_, err = e.Remove(ctx,
+			amount, dst, err := e.Remove(ctx,
 				test.src.uid,
 				test.src.account,
 			)
-			require.Equal(t, test.dst, err)
+			require.Equal(t, test.dst.err, err)
+			assert.Equal(t, test.dst.amount, amount)
+			assert.Equal(t, test.dst.dst, dst)
+		})
+	}
+}
+
+func TestEngine_Summary(t *testing.T) {
+	type Dst struct {
+		count  int64
+		amount float32
+	}
+
+	type Test struct {
+		init string
+		dst  Dst
+	}
+
+	tests := map[string]Test{
+		"Empty table must be counted": {
+			dst: Dst{},
+		},
+		"Active holds must be summed": {
+			init: `
+INSERT INTO asset SET uid = 1, account = 1, amount = 10;
+INSERT INTO asset SET uid = 2, account = 1, amount = 15;`,
+			dst: Dst{
+				count:  2,
+				amount: 25,
+			},
+		},
+	}
+
+	ctx, db := setUp()
+	defer db.Close(ctx)
+
+	e := New(db)
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			query := `
+DELETE FROM account;
+INSERT INTO account SET id = 1; 
+DELETE FROM asset;
+` + test.init
+			_, err := db.Exec(query)
+			require.NoError(t, err)
+
+			count, amount, err := e.Summary(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, test.dst.count, count)
+			assert.Equal(t, test.dst.amount, amount)
 		})
 	}
 }
@@ -4,36 +4,52 @@ import (
 	"billing/domain"
 	"context"
 	"github.com/adverax/echo/database/sql"
+	"math"
 )
 
 type HistoryManager interface {
 	Append(ctx context.Context, uid int64, account uint32, amount float32, op domain.Operation) error
+	AppendPeer(ctx context.Context, peer string, uid int64, account uint32, amount float32, op domain.Operation) error
+	List(ctx context.Context, account uint32, offset, limit int) ([]*domain.HistoryRecord, error)
+	Lock(ctx context.Context, uid int64, account uint32) ([]*domain.HistoryRecord, error)
 }
 
 type AccountManager interface {
 	Credit(ctx context.Context, account uint32, amount float32) error
 	Debit(ctx context.Context, account uint32, amount float32) error
+	Balance(ctx context.Context, account uint32) (amount float32, err error)
 }
 
 type AssetManager interface {
-	Append(ctx context.Context, uid int64, account uint32, amount float32) error
-	Remove(ctx context.Context, uid int64, account uint32) (amount float32, err error)
+	Append(ctx context.Context, uid int64, account uint32, dst uint32, amount float32) error
+	Remove(ctx context.Context, uid int64, account uint32) (amount float32, dst uint32, err error)
+}
+
+type ReversalManager interface {
+	Create(ctx context.Context, reversal *domain.Reversal) error
+	Exists(ctx context.Context, uid int64, account uint32) (bool, error)
+	Reversed(ctx context.Context, original int64, account uint32) (float32, error)
 }
 
 type Manager interface {
 	Credit(ctx context.Context, uid int64, account uint32, amount float32) error
 	Debit(ctx context.Context, uid int64, account uint32, amount float32) error
 	Transfer(ctx context.Context, uid int64, src, dst uint32, amount float32) error
-	Acquire(ctx context.Context, uid int64, account uint32, amount float32) error
+	Deposit(ctx context.Context, peer string, uid int64, account uint32, amount float32) error
+	Acquire(ctx context.Context, uid int64, account uint32, dst uint32, amount float32) error
 	Commit(ctx context.Context, uid int64, account uint32) error
 	Rollback(ctx context.Context, uid int64, account uint32) error
+	Reverse(ctx context.Context, reversal *domain.Reversal) error
+	Balance(ctx context.Context, account uint32) (amount float32, err error)
+	History(ctx context.Context, account uint32, offset, limit int) ([]*domain.HistoryRecord, error)
 }
 
 type engine struct {
 	sql.Repository
-	accounts AccountManager
-	assets   AssetManager
-	history  HistoryManager
+	accounts  AccountManager
+	assets    AssetManager
+	history   HistoryManager
+	reversals ReversalManager
 }
 
 func (engine *engine) Credit(
@@ -103,15 +119,54 @@ func (engine *engine) Transfer(
 	)
 }
 
+// Receive amount of the transfer from the peer instance.
+// Uid of the transfer is unique within the peer, so it does not collide with the local operations.
+func (engine *engine) Deposit(
+	ctx context.Context,
+	peer string,
+	uid int64,
+	account uint32,
+	amount float32,
+) error {
+	return engine.Transaction(
+		ctx,
+		func(ctx context.Context) error {
+			// Missing account is reported as not found, so the peer compensates the transfer
+			_, err := engine.accounts.Balance(ctx, account)
+			if err != nil {
+				return err
+			}
+
+			err = engine.history.AppendPeer(ctx, peer, uid, account, amount, domain.OperationTransferDst)
+			if err != nil {
+				return err
+			}
+
+			return engine.accounts.Debit(ctx, account, amount)
+		},
+	)
+}
+
+// Hold amount of the account.
+// Commit moves the amount to the beneficiary dst (0 for none), Rollback returns it to the account.
 func (engine *engine) Acquire(
 	ctx context.Context,
 	uid int64,
 	account uint32,
+	dst uint32,
 	amount float32,
 ) error {
 	return engine.Transaction(
 		ctx,
 		func(ctx context.Context) error {
+			// Missing beneficiary is rejected now, otherwise the commit fails
+			if dst != 0 {
+				_, err := engine.accounts.Balance(ctx, dst)
+				if err != nil {
+					return err
+				}
+			}
+
 			err := engine.history.Append(ctx, uid, account, amount, domain.OperationAcquire)
 			if err != nil {
 				return err
@@ -122,7 +177,7 @@ func (engine *engine) Acquire(
 				return err
 			}
 
-			return engine.assets.Append(ctx, uid, account, amount)
+			return engine.assets.Append(ctx, uid, account, dst, amount)
 		},
 	)
 }
@@ -135,12 +190,22 @@ func (engine *engine) Commit(
 	return engine.Transaction(
 		ctx,
 		func(ctx context.Context) error {
-			amount, err := engine.assets.Remove(ctx, uid, account)
+			amount, dst, err := engine.assets.Remove(ctx, uid, account)
+			if err != nil {
+				return err
+			}
+
+			err = engine.history.Append(ctx, uid, account, amount, domain.OperationCommit)
+			if err != nil || dst == 0 {
+				return err
+			}
+
+			err = engine.history.Append(ctx, uid, dst, amount, domain.OperationCommitDst)
 			if err != nil {
 				return err
 			}
 
-			return engine.history.Append(ctx, uid, account, amount, domain.OperationCommit)
+			return engine.accounts.Debit(ctx, dst, amount)
 		},
 	)
 }
@@ -153,7 +218,7 @@ func (engine *engine) Rollback(
 	return engine.Transaction(
 		ctx,
 		func(ctx context.Context) error {
-			amount, err := engine.assets.Remove(ctx, uid, account)
+			amount, _, err := engine.assets.Remove(ctx, uid, account)
 			if err != nil {
 				return err
 			}
@@ -168,16 +233,155 @@ func (engine *engine) Rollback(
 	)
 }
 
+// Compensate the original Credit, Debit or Transfer partially (Amount > 0) or fully (Amount = 0).
+// Transfer must be reversed with its recipient as Dst. Reversals of the original can not exceed it.
+func (engine *engine) Reverse(
+	ctx context.Context,
+	reversal *domain.Reversal,
+) error {
+	return engine.Transaction(
+		ctx,
+		func(ctx context.Context) error {
+			original, err := engine.original(ctx, reversal)
+			if err != nil {
+				return err
+			}
+
+			// Repeated reversal is deprecated, even if it has consumed the rest of the original.
+			// Reversals of the original are serialized by the lock of the original.
+			exists, err := engine.reversals.Exists(ctx, reversal.Uid, reversal.Account)
+			if err != nil {
+				return err
+			}
+			if exists {
+				return domain.ErrOperationIsDeprecated
+			}
+
+			reversed, err := engine.reversals.Reversed(ctx, reversal.Original, reversal.Account)
+			if err != nil {
+				return err
+			}
+
+			rest := round(original.Amount - reversed)
+			r := *reversal
+			if r.Amount == 0 {
+				r.Amount = rest
+			}
+			if rest <= 0 || round(r.Amount) > rest {
+				return domain.ErrReversalExceeded
+			}
+
+			err = engine.reversals.Create(ctx, &r)
+			if err != nil {
+				return err
+			}
+
+			err = engine.history.Append(ctx, r.Uid, r.Account, r.Amount, domain.OperationReverse)
+			if err != nil {
+				return err
+			}
+
+			switch original.Op {
+			case domain.OperationCredit:
+				return engine.accounts.Debit(ctx, r.Account, r.Amount)
+			case domain.OperationDebit:
+				return engine.accounts.Credit(ctx, r.Account, r.Amount)
+			}
+
+			err = engine.history.Append(ctx, r.Uid, r.Dst, r.Amount, domain.OperationReverseDst)
+			if err != nil {
+				return err
+			}
+
+			err = engine.accounts.Credit(ctx, r.Dst, r.Amount)
+			if err != nil {
+				return err
+			}
+
+			return engine.accounts.Debit(ctx, r.Account, r.Amount)
+		},
+	)
+}
+
+// Find and lock reversible operation of the account.
+// Transfer is found only together with the receipt of its recipient in this instance.
+func (engine *engine) original(
+	ctx context.Context,
+	reversal *domain.Reversal,
+) (*domain.HistoryRecord, error) {
+	records, err := engine.history.Lock(ctx, reversal.Original, reversal.Account)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, record := range records {
+		switch record.Op {
+		case domain.OperationCredit, domain.OperationDebit:
+			if reversal.Dst == 0 {
+				return record, nil
+			}
+		case domain.OperationTransferSrc:
+			if reversal.Dst == 0 {
+				continue
+			}
+			receipts, err := engine.history.Lock(ctx, reversal.Original, reversal.Dst)
+			if err != nil {
+				return nil, err
+			}
+			for _, receipt := range receipts {
+				if receipt.Op == domain.OperationTransferDst && receipt.Peer == "" {
+					return record, nil
+				}
+			}
+		}
+	}
+
+	return nil, domain.ErrOriginalNotFound
+}
+
+func (engine *engine) Balance(
+	ctx context.Context,
+	account uint32,
+) (amount float32, err error) {
+	return engine.accounts.Balance(ctx, account)
+}
+
+func (engine *engine) History(
+	ctx context.Context,
+	account uint32,
+	offset, limit int,
+) ([]*domain.HistoryRecord, error) {
+	return engine.history.List(ctx, account, offset, limit)
+}
+
 func New(
 	db sql.DB,
 	accounts AccountManager,
 	assets AssetManager,
 	history HistoryManager,
+	reversals ReversalManager,
+) Manager {
+	return NewWithRepository(sql.NewRepository(db), accounts, assets, history, reversals)
+}
+
+// Create banker over the custom repository (e.g. instrumented one)
+func NewWithRepository(
+	repository sql.Repository,
+	accounts AccountManager,
+	assets AssetManager,
+	history HistoryManager,
+	reversals ReversalManager,
 ) Manager {
 	return &engine{
-		Repository: sql.NewRepository(db),
+		Repository: repository,
 		accounts:   accounts,
 		assets:     assets,
 		history:    history,
+		reversals:  reversals,
 	}
 }
+
+// Round amount to the precision of the database
+func round(amount float32) float32 {
+	return float32(math.Round(float64(amount)*1000) / 1000)
+}
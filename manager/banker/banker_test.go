@@ -0,0 +1,209 @@
+package banker
+
+import (
+	"billing/domain"
+	"context"
+	"github.com/adverax/echo/database/sql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type repositoryMock struct {
+	sql.Repository
+}
+
+func (r *repositoryMock) Transaction(ctx context.Context, action func(ctx context.Context) error) error {
+	return action(ctx)
+}
+
+type accountsMock struct {
+	balances map[uint32]float32
+}
+
+func (m *accountsMock) Credit(ctx context.Context, account uint32, amount float32) error {
+	if m.balances[account] < amount {
+		return domain.ErrNoMoney
+	}
+	m.balances[account] -= amount
+	return nil
+}
+
+func (m *accountsMock) Debit(ctx context.Context, account uint32, amount float32) error {
+	m.balances[account] += amount
+	return nil
+}
+
+func (m *accountsMock) Balance(ctx context.Context, account uint32) (float32, error) {
+	sum, ok := m.balances[account]
+	if !ok {
+		return 0, sql.ErrNoRows
+	}
+	return sum, nil
+}
+
+type historyMock struct {
+	HistoryManager
+	records []*domain.HistoryRecord
+}
+
+func (m *historyMock) Append(ctx context.Context, uid int64, account uint32, amount float32, op domain.Operation) error {
+	for _, record := range m.records {
+		if record.Uid == uid && record.Account == account && record.Op == op {
+			return domain.ErrOperationIsDeprecated
+		}
+	}
+	m.records = append(m.records, &domain.HistoryRecord{Uid: uid, Account: account, Amount: amount, Op: op})
+	return nil
+}
+
+func (m *historyMock) Lock(ctx context.Context, uid int64, account uint32) (records []*domain.HistoryRecord, err error) {
+	for _, record := range m.records {
+		if record.Uid == uid && record.Account == account {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+type assetsMock struct {
+	holds map[int64]uint32 // Beneficiaries of the holds by uid
+}
+
+func (m *assetsMock) Append(ctx context.Context, uid int64, account uint32, dst uint32, amount float32) error {
+	m.holds[uid] = dst
+	return nil
+}
+
+func (m *assetsMock) Remove(ctx context.Context, uid int64, account uint32) (float32, uint32, error) {
+	dst, ok := m.holds[uid]
+	if !ok {
+		return 0, 0, sql.ErrNoRows
+	}
+	delete(m.holds, uid)
+	return 0, dst, nil
+}
+
+type reversalsMock struct {
+	reversals []domain.Reversal
+}
+
+func (m *reversalsMock) Create(ctx context.Context, reversal *domain.Reversal) error {
+	exists, _ := m.Exists(ctx, reversal.Uid, reversal.Account)
+	if exists {
+		return domain.ErrOperationIsDeprecated
+	}
+	m.reversals = append(m.reversals, *reversal)
+	return nil
+}
+
+func (m *reversalsMock) Exists(ctx context.Context, uid int64, account uint32) (bool, error) {
+	for _, reversal := range m.reversals {
+		if reversal.Uid == uid && reversal.Account == account {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *reversalsMock) Reversed(ctx context.Context, original int64, account uint32) (amount float32, err error) {
+	for _, reversal := range m.reversals {
+		if reversal.Original == original && reversal.Account == account {
+			amount += reversal.Amount
+		}
+	}
+	return amount, nil
+}
+
+func TestEngine_Acquire(t *testing.T) {
+	type Dst struct {
+		err     error
+		balance float32
+		holds   map[int64]uint32
+	}
+
+	type Test struct {
+		src uint32 // Beneficiary of the hold
+		dst Dst
+	}
+
+	tests := map[string]Test{
+		"Hold with the beneficiary must be acquired": {
+			src: 2,
+			dst: Dst{balance: 6, holds: map[int64]uint32{1: 2}},
+		},
+		"Hold without the beneficiary must be acquired": {
+			dst: Dst{balance: 6, holds: map[int64]uint32{1: 0}},
+		},
+		"Hold with the missing beneficiary must be rejected": {
+			src: 3,
+			dst: Dst{err: sql.ErrNoRows, balance: 10, holds: map[int64]uint32{}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			accounts := &accountsMock{balances: map[uint32]float32{1: 10, 2: 0}}
+			assets := &assetsMock{holds: make(map[int64]uint32)}
+			history := &historyMock{}
+			e := NewWithRepository(&repositoryMock{}, accounts, assets, history, nil)
+
+			err := e.Acquire(context.Background(), 1, 1, test.src, 4)
+			require.Equal(t, test.dst.err, err)
+			assert.Equal(t, test.dst.balance, accounts.balances[1])
+			assert.Equal(t, test.dst.holds, assets.holds)
+			if err != nil {
+				assert.Empty(t, history.records, "rejected hold must not be written to the history")
+			}
+		})
+	}
+}
+
+func TestEngine_Reverse(t *testing.T) {
+	type Test struct {
+		src []domain.Reversal
+		dst []error
+	}
+
+	tests := map[string]Test{
+		"Retry of the final reversal must be deprecated": {
+			src: []domain.Reversal{
+				{Uid: 2, Original: 1, Account: 1},
+				{Uid: 2, Original: 1, Account: 1},
+			},
+			dst: []error{nil, domain.ErrOperationIsDeprecated},
+		},
+		"Retry of the partial reversal must be deprecated": {
+			src: []domain.Reversal{
+				{Uid: 2, Original: 1, Account: 1, Amount: 4},
+				{Uid: 2, Original: 1, Account: 1, Amount: 4},
+				{Uid: 3, Original: 1, Account: 1},
+			},
+			dst: []error{nil, domain.ErrOperationIsDeprecated, nil},
+		},
+		"Reversal of the consumed original must be exceeded": {
+			src: []domain.Reversal{
+				{Uid: 2, Original: 1, Account: 1},
+				{Uid: 3, Original: 1, Account: 1, Amount: 1},
+			},
+			dst: []error{nil, domain.ErrReversalExceeded},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			accounts := &accountsMock{balances: map[uint32]float32{1: 10}}
+			e := NewWithRepository(&repositoryMock{}, accounts, nil, &historyMock{}, &reversalsMock{})
+
+			ctx := context.Background()
+			require.NoError(t, e.Credit(ctx, 1, 1, 10))
+
+			var errs []error
+			for i := range test.src {
+				errs = append(errs, e.Reverse(ctx, &test.src[i]))
+			}
+			assert.Equal(t, test.dst, errs)
+			assert.Equal(t, float32(10), accounts.balances[1], "original must be reversed once")
+		})
+	}
+}
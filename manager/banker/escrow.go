@@ -0,0 +1,170 @@
+package banker
+
+import (
+	"billing/domain"
+	"context"
+	"github.com/adverax/echo/database/sql"
+)
+
+type EscrowManager interface {
+	Create(ctx context.Context, id int64, account uint32, amount float32) error
+	Withdraw(ctx context.Context, id int64, account uint32, amount float32) error
+	Append(ctx context.Context, id int64, buyer uint32, uid int64, account uint32, amount float32, op domain.Operation) error
+	Get(ctx context.Context, id int64, account uint32) (*domain.Escrow, error)
+	Records(ctx context.Context, id int64, buyer uint32) ([]*domain.EscrowRecord, error)
+}
+
+// Escrow keeps funds of the buyer until they are released to the sellers or refunded.
+// Escrow is identified by id and account of the buyer.
+type Escrow interface {
+	Open(ctx context.Context, uid int64, id int64, account uint32, amount float32) error
+	Release(ctx context.Context, uid int64, id int64, account uint32, payouts []domain.Payout) error
+	Refund(ctx context.Context, uid int64, id int64, account uint32, amount float32) error
+	Get(ctx context.Context, id int64, account uint32) (*domain.Escrow, []*domain.EscrowRecord, error)
+}
+
+type escrowEngine struct {
+	sql.Repository
+	accounts AccountManager
+	history  HistoryManager
+	escrows  EscrowManager
+}
+
+// Move amount of the buyer into the new escrow
+func (engine *escrowEngine) Open(
+	ctx context.Context,
+	uid int64,
+	id int64,
+	account uint32,
+	amount float32,
+) error {
+	return engine.Transaction(
+		ctx,
+		func(ctx context.Context) error {
+			err := engine.history.Append(ctx, uid, account, amount, domain.OperationEscrowOpen)
+			if err != nil {
+				return err
+			}
+
+			err = engine.accounts.Credit(ctx, account, amount)
+			if err != nil {
+				return err
+			}
+
+			err = engine.escrows.Create(ctx, id, account, amount)
+			if err != nil {
+				return err
+			}
+
+			return engine.escrows.Append(ctx, id, account, uid, account, amount, domain.OperationEscrowOpen)
+		},
+	)
+}
+
+// Release parts of the escrow to the sellers
+func (engine *escrowEngine) Release(
+	ctx context.Context,
+	uid int64,
+	id int64,
+	account uint32,
+	payouts []domain.Payout,
+) error {
+	return engine.Transaction(
+		ctx,
+		func(ctx context.Context) error {
+			var total float32
+			for _, payout := range payouts {
+				err := engine.escrows.Append(ctx, id, account, uid, payout.Account, payout.Amount, domain.OperationEscrowRelease)
+				if err != nil {
+					return err
+				}
+				total += payout.Amount
+			}
+
+			// Sum of the fractions is not exact in float32
+			err := engine.escrows.Withdraw(ctx, id, account, round(total))
+			if err != nil {
+				return err
+			}
+
+			for _, payout := range payouts {
+				err := engine.history.Append(ctx, uid, payout.Account, payout.Amount, domain.OperationEscrowRelease)
+				if err != nil {
+					return err
+				}
+
+				err = engine.accounts.Debit(ctx, payout.Account, payout.Amount)
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	)
+}
+
+// Return part of the escrow to the buyer
+func (engine *escrowEngine) Refund(
+	ctx context.Context,
+	uid int64,
+	id int64,
+	account uint32,
+	amount float32,
+) error {
+	return engine.Transaction(
+		ctx,
+		func(ctx context.Context) error {
+			err := engine.escrows.Append(ctx, id, account, uid, account, amount, domain.OperationEscrowRefund)
+			if err != nil {
+				return err
+			}
+
+			err = engine.escrows.Withdraw(ctx, id, account, amount)
+			if err != nil {
+				return err
+			}
+
+			err = engine.history.Append(ctx, uid, account, amount, domain.OperationEscrowRefund)
+			if err != nil {
+				return err
+			}
+
+			return engine.accounts.Debit(ctx, account, amount)
+		},
+	)
+}
+
+// Get escrow with its movements
+func (engine *escrowEngine) Get(
+	ctx context.Context,
+	id int64,
+	account uint32,
+) (*domain.Escrow, []*domain.EscrowRecord, error) {
+	escrow, err := engine.escrows.Get(ctx, id, account)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	records, err := engine.escrows.Records(ctx, id, account)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return escrow, records, nil
+}
+
+// Create escrow over the custom repository (e.g. instrumented one)
+func NewEscrow(
+	repository sql.Repository,
+	accounts AccountManager,
+	history HistoryManager,
+	escrows EscrowManager,
+) Escrow {
+	return &escrowEngine{
+		Repository: repository,
+		accounts:   accounts,
+		history:    history,
+		escrows:    escrows,
+	}
+}
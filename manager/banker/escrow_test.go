@@ -0,0 +1,95 @@
+package banker
+
+import (
+	"billing/domain"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type escrowsMock struct {
+	EscrowManager
+	balance float32
+}
+
+// Balance is compared as the decimal column of the database does
+func (m *escrowsMock) Withdraw(ctx context.Context, id int64, account uint32, amount float32) error {
+	if m.balance < amount {
+		return domain.ErrNoMoney
+	}
+	m.balance = round(m.balance - amount)
+	return nil
+}
+
+func (m *escrowsMock) Append(ctx context.Context, id int64, buyer uint32, uid int64, account uint32, amount float32, op domain.Operation) error {
+	return nil
+}
+
+func TestEscrowEngine_Release(t *testing.T) {
+	type Src struct {
+		balance float32
+		payouts []domain.Payout
+	}
+
+	type Dst struct {
+		balance float32
+		err     error
+	}
+
+	type Test struct {
+		src Src
+		dst Dst
+	}
+
+	tests := map[string]Test{
+		"Fractional payouts of the whole balance must be released": {
+			src: Src{
+				balance: 0.9,
+				payouts: []domain.Payout{
+					{Account: 2, Amount: 0.1},
+					{Account: 3, Amount: 0.2},
+					{Account: 4, Amount: 0.6},
+				},
+			},
+			dst: Dst{balance: 0},
+		},
+		"Fractional payouts of the part of the balance must be released": {
+			src: Src{
+				balance: 10.01,
+				payouts: []domain.Payout{
+					{Account: 2, Amount: 3.337},
+					{Account: 3, Amount: 3.337},
+					{Account: 4, Amount: 3.336},
+				},
+			},
+			dst: Dst{balance: 0},
+		},
+		"Excess of the balance must be rejected": {
+			src: Src{
+				balance: 0.9,
+				payouts: []domain.Payout{
+					{Account: 2, Amount: 0.3},
+					{Account: 3, Amount: 0.601},
+				},
+			},
+			dst: Dst{balance: 0.9, err: domain.ErrNoMoney},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			escrows := &escrowsMock{balance: test.src.balance}
+			e := NewEscrow(
+				&repositoryMock{},
+				&accountsMock{balances: make(map[uint32]float32)},
+				&historyMock{},
+				escrows,
+			)
+
+			err := e.Release(context.Background(), 1, 1, 1, test.src.payouts)
+			require.Equal(t, test.dst.err, err)
+			assert.Equal(t, test.dst.balance, escrows.balance)
+		})
+	}
+}
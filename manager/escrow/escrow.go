@@ -0,0 +1,178 @@
+package escrow
+
+import (
+	"billing/domain"
+	"context"
+	"github.com/adverax/echo/database/sql"
+	"time"
+)
+
+type Manager interface {
+	Create(
+		ctx context.Context,
+		id int64,
+		account uint32,
+		amount float32,
+	) error
+	Withdraw(
+		ctx context.Context,
+		id int64,
+		account uint32,
+		amount float32,
+	) error
+	Append(
+		ctx context.Context,
+		id int64,
+		buyer uint32,
+		uid int64,
+		account uint32,
+		amount float32,
+		op domain.Operation,
+	) error
+	Get(
+		ctx context.Context,
+		id int64,
+		account uint32,
+	) (*domain.Escrow, error)
+	Records(
+		ctx context.Context,
+		id int64,
+		buyer uint32,
+	) ([]*domain.EscrowRecord, error)
+}
+
+type engine struct {
+	sql.Repository
+}
+
+func (engine *engine) Create(
+	ctx context.Context,
+	id int64,
+	account uint32,
+	amount float32,
+) error {
+	const query = "INSERT INTO escrow SET id = ?, account = ?, amount = ?, balance = ?"
+	ctx, span := domain.StartQuerySpan(ctx, "escrow.create", query)
+	_, err := engine.Scope(ctx).ExecContext(ctx, query, id, account, amount, amount)
+	domain.EndSpan(span, err)
+	return domain.HandleDeprecatedError(err)
+}
+
+// Withdraw amount from the balance of the escrow
+func (engine *engine) Withdraw(
+	ctx context.Context,
+	id int64,
+	account uint32,
+	amount float32,
+) error {
+	return engine.Transaction(
+		ctx,
+		func(ctx context.Context) error {
+			scope := engine.Scope(ctx)
+
+			const query1 = "SELECT balance FROM escrow WHERE id = ? AND account = ? FOR UPDATE"
+			var balance float32
+			ctx1, span := domain.StartQuerySpan(ctx, "escrow.lock", query1)
+			err := scope.QueryRowContext(ctx1, query1, id, account).Scan(&balance)
+			domain.EndSpan(span, err)
+			if err != nil {
+				return err
+			}
+
+			if balance < amount {
+				return domain.ErrNoMoney
+			}
+
+			const query2 = "UPDATE escrow SET balance = ? WHERE id = ? AND account = ?"
+			ctx2, span := domain.StartQuerySpan(ctx, "escrow.update", query2)
+			_, err = scope.ExecContext(ctx2, query2, balance-amount, id, account)
+			domain.EndSpan(span, err)
+			return err
+		},
+	)
+}
+
+// Register movement of the escrow of the buyer
+func (engine *engine) Append(
+	ctx context.Context,
+	id int64,
+	buyer uint32,
+	uid int64,
+	account uint32,
+	amount float32,
+	op domain.Operation,
+) error {
+	const query = "INSERT INTO escrow_log SET escrow = ?, buyer = ?, uid = ?, account = ?, amount = ?, op = ?"
+	ctx, span := domain.StartQuerySpan(ctx, "escrow.append", query)
+	_, err := engine.Scope(ctx).ExecContext(ctx, query, id, buyer, uid, account, amount, op)
+	domain.EndSpan(span, err)
+	return domain.HandleDeprecatedError(err)
+}
+
+func (engine *engine) Get(
+	ctx context.Context,
+	id int64,
+	account uint32,
+) (*domain.Escrow, error) {
+	const query = "SELECT id, account, amount, balance, UNIX_TIMESTAMP(created) FROM escrow WHERE id = ? AND account = ?"
+	ctx, span := domain.StartQuerySpan(ctx, "escrow.get", query)
+	var escrow domain.Escrow
+	var created int64
+	err := engine.Scope(ctx).QueryRowContext(ctx, query, id, account).Scan(
+		&escrow.Id,
+		&escrow.Account,
+		&escrow.Amount,
+		&escrow.Balance,
+		&created,
+	)
+	domain.EndSpan(span, err)
+	if err != nil {
+		return nil, err
+	}
+	escrow.Created = time.Unix(created, 0)
+	return &escrow, nil
+}
+
+// List movements of the escrow of the buyer (oldest first)
+func (engine *engine) Records(
+	ctx context.Context,
+	id int64,
+	buyer uint32,
+) (records []*domain.EscrowRecord, err error) {
+	const query = "SELECT uid, account, amount, op, UNIX_TIMESTAMP(registered) FROM escrow_log WHERE escrow = ? AND buyer = ? ORDER BY id"
+	ctx, span := domain.StartQuerySpan(ctx, "escrow.records", query)
+	defer func() {
+		domain.EndSpan(span, err)
+	}()
+
+	rows, err := engine.Scope(ctx).QueryContext(ctx, query, id, buyer)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var record domain.EscrowRecord
+		var registered int64
+		err := rows.Scan(
+			&record.Uid,
+			&record.Account,
+			&record.Amount,
+			&record.Op,
+			&registered,
+		)
+		if err != nil {
+			return nil, err
+		}
+		record.Registered = time.Unix(registered, 0)
+		records = append(records, &record)
+	}
+
+	return records, rows.Err()
+}
+
+func New(db sql.DB) Manager {
+	return &engine{
+		Repository: sql.NewRepository(db),
+	}
+}
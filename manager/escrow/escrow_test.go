@@ -0,0 +1,155 @@
+package escrow
+
+import (
+	"billing/domain"
+	"context"
+	"github.com/adverax/echo/database/sql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+)
+
+func setUp() (context.Context, sql.DB) {
+	ctx := context.Background()
+	config, err := domain.LoadConfig(nil, os.Environ())
+	if err != nil {
+		panic(err)
+	}
+	return ctx, config.Database.DSC().OpenForTest(ctx)
+}
+
+const initQuery = `
+DELETE FROM account;
+INSERT INTO account SET id = 1;
+INSERT INTO account SET id = 2;
+DELETE FROM escrow;
+INSERT INTO escrow SET id = 1, account = 1, amount = 30, balance = 20;
+INSERT INTO escrow_log SET escrow = 1, buyer = 1, uid = 1, account = 1, amount = 30, op = 10;
+`
+
+func TestEngine_Create(t *testing.T) {
+	type Src struct {
+		id      int64
+		account uint32
+	}
+
+	type Test struct {
+		src Src
+		dst error
+	}
+
+	tests := map[string]Test{
+		"Unique escrow must be accepted": {
+			src: Src{id: 2, account: 1},
+		},
+		"Escrow with the id of the other buyer must be accepted": {
+			src: Src{id: 1, account: 2},
+		},
+		"Duplicated escrow must be rejected": {
+			src: Src{id: 1, account: 1},
+			dst: domain.ErrOperationIsDeprecated,
+		},
+	}
+
+	ctx, db := setUp()
+	defer db.Close(ctx)
+
+	e := New(db)
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := db.Exec(initQuery)
+			require.NoError(t, err)
+
+			err = e.Create(ctx, test.src.id, test.src.account, 10)
+			require.Equal(t, test.dst, err)
+		})
+	}
+}
+
+func TestEngine_Withdraw(t *testing.T) {
+	type Src struct {
+		account uint32
+		amount  float32
+	}
+
+	type Dst struct {
+		balance float32
+		err     error
+	}
+
+	type Test struct {
+		src Src
+		dst Dst
+	}
+
+	tests := map[string]Test{
+		"Part of the balance must be withdrawn": {
+			src: Src{account: 1, amount: 5},
+			dst: Dst{balance: 15},
+		},
+		"Whole balance must be withdrawn": {
+			src: Src{account: 1, amount: 20},
+			dst: Dst{balance: 0},
+		},
+		"Excess of the balance must be rejected": {
+			src: Src{account: 1, amount: 25},
+			dst: Dst{err: domain.ErrNoMoney, balance: 20},
+		},
+		"Escrow of the other account must be not found": {
+			src: Src{account: 2, amount: 5},
+			dst: Dst{err: sql.ErrNoRows, balance: 20},
+		},
+	}
+
+	ctx, db := setUp()
+	defer db.Close(ctx)
+
+	e := New(db)
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := db.Exec(initQuery)
+			require.NoError(t, err)
+
+			err = e.Withdraw(ctx, 1, test.src.account, test.src.amount)
+			require.Equal(t, test.dst.err, err)
+
+			escrow, err := e.Get(ctx, 1, 1)
+			require.NoError(t, err)
+			assert.Equal(t, test.dst.balance, escrow.Balance)
+		})
+	}
+}
+
+func TestEngine_Append(t *testing.T) {
+	ctx, db := setUp()
+	defer db.Close(ctx)
+
+	e := New(db)
+
+	_, err := db.Exec(initQuery)
+	require.NoError(t, err)
+
+	err = e.Append(ctx, 1, 1, 2, 5, 10, domain.OperationEscrowRelease)
+	require.NoError(t, err)
+	err = e.Append(ctx, 1, 1, 2, 5, 10, domain.OperationEscrowRelease)
+	require.Equal(t, domain.ErrOperationIsDeprecated, err)
+
+	records, err := e.Records(ctx, 1, 1)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, domain.OperationEscrowOpen, records[0].Op)
+	assert.Equal(t, uint32(5), records[1].Account)
+	assert.Equal(t, float32(10), records[1].Amount)
+
+	// Escrow of the other buyer with the same id is separate
+	err = e.Create(ctx, 1, 2, 10)
+	require.NoError(t, err)
+	err = e.Append(ctx, 1, 2, 2, 5, 10, domain.OperationEscrowRelease)
+	require.NoError(t, err)
+	records, err = e.Records(ctx, 1, 2)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+}
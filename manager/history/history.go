@@ -4,6 +4,7 @@ import (
 	"billing/domain"
 	"context"
 	"github.com/adverax/echo/database/sql"
+	"time"
 )
 
 type Manager interface {
@@ -14,10 +15,29 @@ type Manager interface {
 		amount float32,
 		op domain.Operation,
 	) error
+	AppendPeer(
+		ctx context.Context,
+		peer string,
+		uid int64,
+		account uint32,
+		amount float32,
+		op domain.Operation,
+	) error
+	List(
+		ctx context.Context,
+		account uint32,
+		offset, limit int,
+	) ([]*domain.HistoryRecord, error)
+	Lock(
+		ctx context.Context,
+		uid int64,
+		account uint32,
+	) ([]*domain.HistoryRecord, error)
 }
 
 type engine struct {
 	sql.Repository
+	reader domain.Reader
 }
 
 func (engine *engine) Append(
@@ -28,12 +48,132 @@ func (engine *engine) Append(
 	op domain.Operation,
 ) error {
 	const query = "INSERT INTO history SET uid = ?, account = ?, amount = ?, op = ?"
-	_, err := engine.Scope(ctx).Exec(query, uid, account, amount, op)
+	ctx, span := domain.StartQuerySpan(ctx, "history.append", query)
+	_, err := engine.Scope(ctx).ExecContext(ctx, query, uid, account, amount, op)
+	domain.EndSpan(span, err)
+	return domain.HandleDeprecatedError(err)
+}
+
+// Register operation, that is sent by the peer instance. Uid is unique within the peer.
+func (engine *engine) AppendPeer(
+	ctx context.Context,
+	peer string,
+	uid int64,
+	account uint32,
+	amount float32,
+	op domain.Operation,
+) error {
+	const query = "INSERT INTO history SET peer = ?, uid = ?, account = ?, amount = ?, op = ?"
+	ctx, span := domain.StartQuerySpan(ctx, "history.append_peer", query)
+	_, err := engine.Scope(ctx).ExecContext(ctx, query, peer, uid, account, amount, op)
+	domain.EndSpan(span, err)
 	return domain.HandleDeprecatedError(err)
 }
 
+// List operations of the account (newest first)
+func (engine *engine) List(
+	ctx context.Context,
+	account uint32,
+	offset, limit int,
+) (records []*domain.HistoryRecord, err error) {
+	const query = "SELECT id, uid, account, amount, op, peer, UNIX_TIMESTAMP(registered) FROM history WHERE account = ? ORDER BY id DESC LIMIT ?, ?"
+	ctx, span := domain.StartQuerySpan(ctx, "history.list", query)
+	defer func() {
+		domain.EndSpan(span, err)
+	}()
+
+	rows, err := engine.reader.Reader(ctx).QueryContext(ctx, query, account, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var record domain.HistoryRecord
+		var registered int64
+		err := rows.Scan(
+			&record.Id,
+			&record.Uid,
+			&record.Account,
+			&record.Amount,
+			&record.Op,
+			&record.Peer,
+			&registered,
+		)
+		if err != nil {
+			return nil, err
+		}
+		record.Registered = time.Unix(registered, 0)
+		records = append(records, &record)
+	}
+
+	return records, rows.Err()
+}
+
+// Lock operations of the account with the uid until the end of the transaction
+func (engine *engine) Lock(
+	ctx context.Context,
+	uid int64,
+	account uint32,
+) (records []*domain.HistoryRecord, err error) {
+	const query = "SELECT id, uid, account, amount, op, peer, UNIX_TIMESTAMP(registered) FROM history WHERE account = ? AND uid = ? ORDER BY id FOR UPDATE"
+	ctx, span := domain.StartQuerySpan(ctx, "history.lock", query)
+	defer func() {
+		domain.EndSpan(span, err)
+	}()
+
+	rows, err := engine.Scope(ctx).QueryContext(ctx, query, account, uid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var record domain.HistoryRecord
+		var registered int64
+		err := rows.Scan(
+			&record.Id,
+			&record.Uid,
+			&record.Account,
+			&record.Amount,
+			&record.Op,
+			&record.Peer,
+			&registered,
+		)
+		if err != nil {
+			return nil, err
+		}
+		record.Registered = time.Unix(registered, 0)
+		records = append(records, &record)
+	}
+
+	return records, rows.Err()
+}
+
 func New(db sql.DB) Manager {
+	repository := sql.NewRepository(db)
+	return &engine{
+		Repository: repository,
+		reader:     primaryReader{repository},
+	}
+}
+
+// Create manager, that lists operations by the reader (e.g. from the replicas)
+func NewWithReader(
+	db sql.DB,
+	reader domain.Reader,
+) Manager {
 	return &engine{
 		Repository: sql.NewRepository(db),
+		reader:     reader,
 	}
 }
+
+// Reader of the database of the repository
+type primaryReader struct {
+	sql.Repository
+}
+
+func (r primaryReader) Reader(ctx context.Context) sql.Scope {
+	return r.Scope(ctx)
+}
@@ -6,12 +6,17 @@ import (
 	"github.com/adverax/echo/database/sql"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"os"
 	"testing"
 )
 
 func setUp() (context.Context, sql.DB) {
 	ctx := context.Background()
-	return ctx, domain.Config.Database.DSC().OpenForTest(ctx)
+	config, err := domain.LoadConfig(nil, os.Environ())
+	if err != nil {
+		panic(err)
+	}
+	return ctx, config.Database.DSC().OpenForTest(ctx)
 }
 
 func TestEngine_Append(t *testing.T) {
@@ -109,3 +114,198 @@ INSERT INTO history SET uid = 1, account = 1, amount = 10, op = 1;
 		})
 	}
 }
+
+func TestEngine_AppendPeer(t *testing.T) {
+	type Src struct {
+		peer string
+		uid  int64
+	}
+
+	type Test struct {
+		src Src
+		dst error
+	}
+
+	tests := map[string]Test{
+		"Uid of the local operation must be accepted": {
+			src: Src{peer: "billing-a", uid: 1},
+		},
+		"Uid of the other peer must be accepted": {
+			src: Src{peer: "billing-b", uid: 2},
+		},
+		"Duplicated uid of the peer must be rejected": {
+			src: Src{peer: "billing-a", uid: 2},
+			dst: domain.ErrOperationIsDeprecated,
+		},
+	}
+
+	ctx, db := setUp()
+	defer db.Close(ctx)
+
+	e := New(db)
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			const query = `
+DELETE FROM account;
+INSERT INTO account SET id = 1;
+DELETE FROM history;
+INSERT INTO history SET uid = 1, account = 1, amount = 10, op = 4;
+INSERT INTO history SET peer = 'billing-a', uid = 2, account = 1, amount = 10, op = 4;
+`
+			_, err := db.Exec(query)
+			require.NoError(t, err)
+
+			err = e.AppendPeer(ctx, test.src.peer, test.src.uid, 1, 20, domain.OperationTransferDst)
+			require.Equal(t, test.dst, err)
+			if test.dst != nil {
+				return
+			}
+
+			const query2 = "SELECT COUNT(*) FROM history WHERE peer = ? AND uid = ?"
+			var count int
+			err = db.QueryRow(query2, test.src.peer, test.src.uid).Scan(&count)
+			require.NoError(t, err)
+			assert.Equal(t, 1, count)
+		})
+	}
+}
+
+func TestEngine_List(t *testing.T) {
+	type Row struct {
+		uid     int64
+		account uint32
+		amount  float32
+		op      domain.Operation
+	}
+
+	type Src struct {
+		account uint32
+		offset  int
+		limit   int
+	}
+
+	type Test struct {
+		src Src
+		dst []Row
+	}
+
+	tests := map[string]Test{
+		"Operations must be listed newest first": {
+			src: Src{account: 1, limit: 10},
+			dst: []Row{
+				{uid: 3, account: 1, amount: 30, op: domain.OperationAcquire},
+				{uid: 2, account: 1, amount: 20, op: domain.OperationDebit},
+				{uid: 1, account: 1, amount: 10, op: domain.OperationCredit},
+			},
+		},
+		"Operations must be paginated": {
+			src: Src{account: 1, offset: 1, limit: 1},
+			dst: []Row{
+				{uid: 2, account: 1, amount: 20, op: domain.OperationDebit},
+			},
+		},
+		"Foreign operations must be skipped": {
+			src: Src{account: 2, limit: 10},
+			dst: []Row{
+				{uid: 4, account: 2, amount: 40, op: domain.OperationCredit},
+			},
+		},
+		"Unknown account must have empty history": {
+			src: Src{account: 3, limit: 10},
+		},
+	}
+
+	ctx, db := setUp()
+	defer db.Close(ctx)
+
+	e := New(db)
+
+	const query = `
+DELETE FROM account;
+INSERT INTO account SET id = 1;
+INSERT INTO account SET id = 2;
+INSERT INTO account SET id = 3;
+DELETE FROM history;
+INSERT INTO history SET uid = 1, account = 1, amount = 10, op = 1;
+INSERT INTO history SET uid = 2, account = 1, amount = 20, op = 2;
+INSERT INTO history SET uid = 3, account = 1, amount = 30, op = 5;
+INSERT INTO history SET uid = 4, account = 2, amount = 40, op = 1;
+`
+	_, err := db.Exec(query)
+	require.NoError(t, err)
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			records, err := e.List(ctx, test.src.account, test.src.offset, test.src.limit)
+			require.NoError(t, err)
+
+			var rows []Row
+			for _, record := range records {
+				rows = append(rows, Row{
+					uid:     record.Uid,
+					account: record.Account,
+					amount:  record.Amount,
+					op:      record.Op,
+				})
+			}
+			assert.Equal(t, test.dst, rows)
+		})
+	}
+}
+
+func TestEngine_Lock(t *testing.T) {
+	type Test struct {
+		uid     int64
+		account uint32
+		dst     []domain.Operation
+	}
+
+	tests := map[string]Test{
+		"Operations of the uid must be locked": {
+			uid:     1,
+			account: 1,
+			dst:     []domain.Operation{domain.OperationTransferSrc, domain.OperationReverse},
+		},
+		"Operations of the other account must be skipped": {
+			uid:     1,
+			account: 2,
+			dst:     []domain.Operation{domain.OperationTransferDst},
+		},
+		"Unknown uid must have no operations": {
+			uid:     5,
+			account: 1,
+		},
+	}
+
+	ctx, db := setUp()
+	defer db.Close(ctx)
+
+	e := New(db)
+
+	const query = `
+DELETE FROM account;
+INSERT INTO account SET id = 1;
+INSERT INTO account SET id = 2;
+DELETE FROM history;
+INSERT INTO history SET uid = 1, account = 1, amount = 10, op = 3;
+INSERT INTO history SET uid = 1, account = 2, amount = 10, op = 4;
+INSERT INTO history SET uid = 1, account = 1, amount = 5, op = 13;
+INSERT INTO history SET uid = 2, account = 1, amount = 20, op = 2;
+`
+	_, err := db.Exec(query)
+	require.NoError(t, err)
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			records, err := e.Lock(ctx, test.uid, test.account)
+			require.NoError(t, err)
+
+			var ops []domain.Operation
+			for _, record := range records {
+				ops = append(ops, record.Op)
+			}
+			assert.Equal(t, test.dst, ops)
+		})
+	}
+}
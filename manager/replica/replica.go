@@ -0,0 +1,155 @@
+package replica
+
+import (
+	"context"
+	std "database/sql"
+	"errors"
+	"github.com/adverax/echo/database/sql"
+	"log/slog"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// Replica is not checked yet, replication is stopped or node is not a replica
+var errNotReplicating = errors.New("replication is not running")
+
+type replica struct {
+	db    sql.DB
+	fresh atomic.Bool // Lag does not exceed the limit
+}
+
+// Router sends read-only queries to the replicas, which lag does not exceed the limit.
+// When there are no such replicas, queries are sent to the primary.
+type Router struct {
+	primary  sql.DB
+	replicas []*replica
+	maxLag   time.Duration
+	logger   *slog.Logger
+	next     atomic.Uint32 // Counter of the round robin
+}
+
+// Get connection for the read-only query
+func (r *Router) Reader(ctx context.Context) sql.Scope {
+	fresh := make([]sql.DB, 0, len(r.replicas))
+	for _, rep := range r.replicas {
+		if rep.fresh.Load() {
+			fresh = append(fresh, rep.db)
+		}
+	}
+	if len(fresh) == 0 {
+		return r.primary
+	}
+	return fresh[int(r.next.Add(1)-1)%len(fresh)]
+}
+
+// Check lag of the replicas every interval until the context is done.
+// Replicas are not used before the first check.
+func (r *Router) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for i, rep := range r.replicas {
+			r.check(ctx, i, rep, interval)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Router) check(
+	ctx context.Context,
+	index int,
+	rep *replica,
+	timeout time.Duration,
+) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	lag, err := Lag(ctx, rep.db)
+	fresh := err == nil && lag <= r.maxLag
+
+	prev := rep.fresh.Swap(fresh)
+	switch {
+	case fresh && !prev:
+		r.logger.Info("Replica is used for reads", "replica", index, "lag", lag)
+	case !fresh && prev && err != nil:
+		r.logger.Warn("Replica is not available, reads are sent to the primary", "replica", index, "error", err)
+	case !fresh && prev:
+		r.logger.Warn("Replica is lagging, reads are sent to the primary", "replica", index, "lag", lag)
+	}
+}
+
+// Get replication lag of the replica (MySQL 8.0.22+ or older)
+func Lag(ctx context.Context, db sql.Scope) (time.Duration, error) {
+	rows, err := db.QueryContext(ctx, "SHOW REPLICA STATUS")
+	if err != nil {
+		rows, err = db.QueryContext(ctx, "SHOW SLAVE STATUS")
+		if err != nil {
+			return 0, err
+		}
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+	if !rows.Next() {
+		err = rows.Err()
+		if err == nil {
+			err = errNotReplicating
+		}
+		return 0, err
+	}
+
+	values := make([]std.RawBytes, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	err = rows.Scan(dest...)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, column := range columns {
+		if column != "Seconds_Behind_Source" && column != "Seconds_Behind_Master" {
+			continue
+		}
+		if values[i] == nil {
+			return 0, errNotReplicating
+		}
+		seconds, err := strconv.ParseInt(string(values[i]), 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(seconds) * time.Second, nil
+	}
+
+	return 0, errNotReplicating
+}
+
+// Create router of the read-only queries.
+// Without replicas all queries are sent to the primary.
+func NewRouter(
+	primary sql.DB,
+	replicas []sql.DB,
+	maxLag time.Duration,
+	logger *slog.Logger,
+) *Router {
+	r := &Router{
+		primary: primary,
+		maxLag:  maxLag,
+		logger:  logger,
+	}
+	for _, db := range replicas {
+		r.replicas = append(r.replicas, &replica{db: db})
+	}
+	return r
+}
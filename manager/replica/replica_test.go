@@ -0,0 +1,63 @@
+package replica
+
+import (
+	"context"
+	"github.com/adverax/echo/database/sql"
+	"github.com/stretchr/testify/assert"
+	"log/slog"
+	"testing"
+)
+
+type dbMock struct {
+	sql.DB
+	name string
+}
+
+func TestRouter_Reader(t *testing.T) {
+	type Src struct {
+		fresh []bool
+	}
+
+	type Test struct {
+		src Src
+		dst []string
+	}
+
+	tests := map[string]Test{
+		"Primary must be used without replicas": {
+			dst: []string{"primary", "primary"},
+		},
+		"Fresh replicas must be used by turns": {
+			src: Src{
+				fresh: []bool{true, false, true},
+			},
+			dst: []string{"replica0", "replica2", "replica0"},
+		},
+		"Primary must be used, when all replicas are lagging": {
+			src: Src{
+				fresh: []bool{false, false},
+			},
+			dst: []string{"primary", "primary"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var replicas []sql.DB
+			for i := range test.src.fresh {
+				replicas = append(replicas, &dbMock{name: "replica" + string(rune('0'+i))})
+			}
+
+			r := NewRouter(&dbMock{name: "primary"}, replicas, 0, slog.New(slog.DiscardHandler))
+			for i, fresh := range test.src.fresh {
+				r.replicas[i].fresh.Store(fresh)
+			}
+
+			var names []string
+			for range test.dst {
+				names = append(names, r.Reader(context.Background()).(*dbMock).name)
+			}
+			assert.Equal(t, test.dst, names)
+		})
+	}
+}
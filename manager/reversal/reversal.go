@@ -0,0 +1,77 @@
+package reversal
+
+import (
+	"billing/domain"
+	"context"
+	"github.com/adverax/echo/database/sql"
+)
+
+type Manager interface {
+	Create(ctx context.Context, reversal *domain.Reversal) error
+	Exists(
+		ctx context.Context,
+		uid int64,
+		account uint32,
+	) (bool, error)
+	Reversed(
+		ctx context.Context,
+		original int64,
+		account uint32,
+	) (float32, error)
+}
+
+type engine struct {
+	sql.Repository
+}
+
+// Register link of the reversal with the original operation
+func (engine *engine) Create(
+	ctx context.Context,
+	reversal *domain.Reversal,
+) error {
+	const query = "INSERT INTO reversal SET uid = ?, original = ?, account = ?, dst = ?, amount = ?"
+	ctx, span := domain.StartQuerySpan(ctx, "reversal.create", query)
+	_, err := engine.Scope(ctx).ExecContext(
+		ctx,
+		query,
+		reversal.Uid,
+		reversal.Original,
+		reversal.Account,
+		reversal.Dst,
+		reversal.Amount,
+	)
+	domain.EndSpan(span, err)
+	return domain.HandleDeprecatedError(err)
+}
+
+// Check, that reversal with the uid is registered for the account
+func (engine *engine) Exists(
+	ctx context.Context,
+	uid int64,
+	account uint32,
+) (exists bool, err error) {
+	const query = "SELECT EXISTS(SELECT 1 FROM reversal WHERE account = ? AND uid = ?)"
+	ctx, span := domain.StartQuerySpan(ctx, "reversal.exists", query)
+	err = engine.Scope(ctx).QueryRowContext(ctx, query, account, uid).Scan(&exists)
+	domain.EndSpan(span, err)
+	return exists, err
+}
+
+// Get total reversed amount of the original operation
+func (engine *engine) Reversed(
+	ctx context.Context,
+	original int64,
+	account uint32,
+) (amount float32, err error) {
+	const query = "SELECT COALESCE(SUM(amount), 0) FROM reversal WHERE account = ? AND original = ?"
+	ctx, span := domain.StartQuerySpan(ctx, "reversal.reversed", query)
+	err = engine.Scope(ctx).QueryRowContext(ctx, query, account, original).Scan(&amount)
+	domain.EndSpan(span, err)
+	return amount, err
+}
+
+func New(db sql.DB) Manager {
+	return &engine{
+		Repository: sql.NewRepository(db),
+	}
+}
@@ -0,0 +1,145 @@
+package reversal
+
+import (
+	"billing/domain"
+	"context"
+	"github.com/adverax/echo/database/sql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+)
+
+func setUp() (context.Context, sql.DB) {
+	ctx := context.Background()
+	config, err := domain.LoadConfig(nil, os.Environ())
+	if err != nil {
+		panic(err)
+	}
+	return ctx, config.Database.DSC().OpenForTest(ctx)
+}
+
+const initQuery = `
+DELETE FROM account;
+INSERT INTO account SET id = 1;
+INSERT INTO account SET id = 2;
+DELETE FROM reversal;
+INSERT INTO reversal SET uid = 10, original = 1, account = 1, amount = 3;
+INSERT INTO reversal SET uid = 11, original = 1, account = 1, amount = 2.5;
+INSERT INTO reversal SET uid = 12, original = 1, account = 2, amount = 7;
+`
+
+func TestEngine_Create(t *testing.T) {
+	type Test struct {
+		src domain.Reversal
+		dst error
+	}
+
+	tests := map[string]Test{
+		"Unique reversal must be accepted": {
+			src: domain.Reversal{Uid: 13, Original: 1, Account: 1, Dst: 2, Amount: 1},
+		},
+		"Duplicated reversal must be rejected": {
+			src: domain.Reversal{Uid: 10, Original: 2, Account: 1, Amount: 1},
+			dst: domain.ErrOperationIsDeprecated,
+		},
+	}
+
+	ctx, db := setUp()
+	defer db.Close(ctx)
+
+	e := New(db)
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := db.Exec(initQuery)
+			require.NoError(t, err)
+
+			err = e.Create(ctx, &test.src)
+			require.Equal(t, test.dst, err)
+		})
+	}
+}
+
+func TestEngine_Exists(t *testing.T) {
+	type Src struct {
+		uid     int64
+		account uint32
+	}
+
+	type Test struct {
+		src Src
+		dst bool
+	}
+
+	tests := map[string]Test{
+		"Registered reversal must exist": {
+			src: Src{uid: 11, account: 1},
+			dst: true,
+		},
+		"Reversal of the other account must not exist": {
+			src: Src{uid: 12, account: 1},
+		},
+		"Unknown reversal must not exist": {
+			src: Src{uid: 13, account: 1},
+		},
+	}
+
+	ctx, db := setUp()
+	defer db.Close(ctx)
+
+	e := New(db)
+
+	_, err := db.Exec(initQuery)
+	require.NoError(t, err)
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			exists, err := e.Exists(ctx, test.src.uid, test.src.account)
+			require.NoError(t, err)
+			assert.Equal(t, test.dst, exists)
+		})
+	}
+}
+
+func TestEngine_Reversed(t *testing.T) {
+	type Src struct {
+		original int64
+		account  uint32
+	}
+
+	type Test struct {
+		src Src
+		dst float32
+	}
+
+	tests := map[string]Test{
+		"Reversals of the original must be summed": {
+			src: Src{original: 1, account: 1},
+			dst: 5.5,
+		},
+		"Reversals of the other account must be skipped": {
+			src: Src{original: 1, account: 2},
+			dst: 7,
+		},
+		"Original without reversals must have zero amount": {
+			src: Src{original: 2, account: 1},
+		},
+	}
+
+	ctx, db := setUp()
+	defer db.Close(ctx)
+
+	e := New(db)
+
+	_, err := db.Exec(initQuery)
+	require.NoError(t, err)
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			amount, err := e.Reversed(ctx, test.src.original, test.src.account)
+			require.NoError(t, err)
+			assert.Equal(t, test.dst, amount)
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package saga
+
+import (
+	"billing/domain"
+	"context"
+	"github.com/adverax/echo/database/sql"
+	"time"
+)
+
+type Manager interface {
+	Create(ctx context.Context, saga *domain.Saga) error
+	Transit(
+		ctx context.Context,
+		uid int64,
+		src uint32,
+		from, to domain.SagaState,
+	) error
+	List(
+		ctx context.Context,
+		state domain.SagaState,
+		age time.Duration,
+		limit int,
+	) ([]*domain.Saga, error)
+}
+
+type engine struct {
+	sql.Repository
+}
+
+func (engine *engine) Create(
+	ctx context.Context,
+	saga *domain.Saga,
+) error {
+	const query = "INSERT INTO saga SET uid = ?, src = ?, dst = ?, amount = ?, state = ?"
+	ctx, span := domain.StartQuerySpan(ctx, "saga.create", query)
+	_, err := engine.Scope(ctx).ExecContext(ctx, query, saga.Uid, saga.Src, saga.Dst, saga.Amount, saga.State)
+	domain.EndSpan(span, err)
+	return domain.HandleDeprecatedError(err)
+}
+
+// Change state of the saga.
+// Returns sql.ErrNoRows, if the saga is not found in the state from (e.g. it is handled by another worker).
+func (engine *engine) Transit(
+	ctx context.Context,
+	uid int64,
+	src uint32,
+	from, to domain.SagaState,
+) error {
+	const query = "UPDATE saga SET state = ? WHERE src = ? AND uid = ? AND state = ?"
+	ctx, span := domain.StartQuerySpan(ctx, "saga.transit", query)
+	res, err := engine.Scope(ctx).ExecContext(ctx, query, to, src, uid, from)
+	if err == nil {
+		var n int64
+		n, err = res.RowsAffected()
+		if err == nil && n == 0 {
+			err = sql.ErrNoRows
+		}
+	}
+	domain.EndSpan(span, err)
+	return err
+}
+
+// List sagas, that are in the state at least for the age (oldest first)
+func (engine *engine) List(
+	ctx context.Context,
+	state domain.SagaState,
+	age time.Duration,
+	limit int,
+) (sagas []*domain.Saga, err error) {
+	const query = "SELECT uid, src, dst, amount, state, UNIX_TIMESTAMP(updated) FROM saga WHERE state = ? AND updated <= NOW() - INTERVAL ? SECOND ORDER BY id LIMIT ?"
+	ctx, span := domain.StartQuerySpan(ctx, "saga.list", query)
+	defer func() {
+		domain.EndSpan(span, err)
+	}()
+
+	rows, err := engine.Scope(ctx).QueryContext(ctx, query, state, int64(age.Seconds()), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var saga domain.Saga
+		var updated int64
+		err := rows.Scan(
+			&saga.Uid,
+			&saga.Src,
+			&saga.Dst,
+			&saga.Amount,
+			&saga.State,
+			&updated,
+		)
+		if err != nil {
+			return nil, err
+		}
+		saga.Updated = time.Unix(updated, 0)
+		sagas = append(sagas, &saga)
+	}
+
+	return sagas, rows.Err()
+}
+
+func New(db sql.DB) Manager {
+	return &engine{
+		Repository: sql.NewRepository(db),
+	}
+}
@@ -0,0 +1,125 @@
+package saga
+
+import (
+	"billing/domain"
+	"context"
+	"github.com/adverax/echo/database/sql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+	"time"
+)
+
+func setUp() (context.Context, sql.DB) {
+	ctx := context.Background()
+	config, err := domain.LoadConfig(nil, os.Environ())
+	if err != nil {
+		panic(err)
+	}
+	return ctx, config.Database.DSC().OpenForTest(ctx)
+}
+
+const initQuery = `
+DELETE FROM account;
+INSERT INTO account SET id = 1;
+DELETE FROM saga;
+INSERT INTO saga SET uid = 1, src = 1, dst = 2, amount = 10, state = 1;
+`
+
+func TestEngine_Create(t *testing.T) {
+	type Test struct {
+		src *domain.Saga
+		dst error
+	}
+
+	tests := map[string]Test{
+		"Unique saga must be accepted": {
+			src: &domain.Saga{Uid: 2, Src: 1, Dst: 2, Amount: 20, State: domain.SagaReserved},
+		},
+		"Duplicated saga must be rejected": {
+			src: &domain.Saga{Uid: 1, Src: 1, Dst: 3, Amount: 20, State: domain.SagaReserved},
+			dst: domain.ErrOperationIsDeprecated,
+		},
+	}
+
+	ctx, db := setUp()
+	defer db.Close(ctx)
+
+	e := New(db)
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := db.Exec(initQuery)
+			require.NoError(t, err)
+
+			err = e.Create(ctx, test.src)
+			require.Equal(t, test.dst, err)
+		})
+	}
+}
+
+func TestEngine_Transit(t *testing.T) {
+	type Src struct {
+		uid  int64
+		from domain.SagaState
+	}
+
+	type Test struct {
+		src Src
+		dst error
+	}
+
+	tests := map[string]Test{
+		"Saga in the expected state must be changed": {
+			src: Src{uid: 1, from: domain.SagaReserved},
+		},
+		"Saga in the other state must be kept": {
+			src: Src{uid: 1, from: domain.SagaCompleted},
+			dst: sql.ErrNoRows,
+		},
+		"Unknown saga must be reported": {
+			src: Src{uid: 2, from: domain.SagaReserved},
+			dst: sql.ErrNoRows,
+		},
+	}
+
+	ctx, db := setUp()
+	defer db.Close(ctx)
+
+	e := New(db)
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := db.Exec(initQuery)
+			require.NoError(t, err)
+
+			err = e.Transit(ctx, test.src.uid, 1, test.src.from, domain.SagaCompleted)
+			require.Equal(t, test.dst, err)
+		})
+	}
+}
+
+func TestEngine_List(t *testing.T) {
+	ctx, db := setUp()
+	defer db.Close(ctx)
+
+	e := New(db)
+
+	_, err := db.Exec(initQuery + `
+INSERT INTO saga SET uid = 2, src = 1, dst = 2, amount = 20, state = 2;
+INSERT INTO saga SET uid = 3, src = 1, dst = 2, amount = 30, state = 1, updated = NOW() - INTERVAL 1 HOUR;
+`)
+	require.NoError(t, err)
+
+	sagas, err := e.List(ctx, domain.SagaReserved, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, sagas, 2)
+	assert.Equal(t, int64(1), sagas[0].Uid)
+	assert.Equal(t, float32(10), sagas[0].Amount)
+
+	sagas, err = e.List(ctx, domain.SagaReserved, time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, sagas, 1)
+	assert.Equal(t, int64(3), sagas[0].Uid)
+}
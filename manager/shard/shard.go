@@ -0,0 +1,536 @@
+package shard
+
+import (
+	"billing/domain"
+	"billing/manager/banker"
+	"billing/manager/saga"
+	"context"
+	"github.com/adverax/echo/database/sql"
+	"hash/fnv"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+// Count of the pending sagas, that are resumed at once
+const resumeLimit = 100
+
+type AssetManager interface {
+	banker.AssetManager
+	Summary(ctx context.Context) (count int64, amount float32, err error)
+}
+
+// Other billing instance
+type Remote interface {
+	Deposit(ctx context.Context, uid int64, account uint32, amount float32) error
+}
+
+// Database of the accounts
+type Shard struct {
+	Name       string
+	Min, Max   uint32 // Range of the accounts (range strategy, Max = 0 for unlimited)
+	Repository sql.Repository
+	Banker     banker.Manager
+	Escrow     banker.Escrow
+	Accounts   banker.AccountManager
+	History    banker.HistoryManager
+	Assets     AssetManager
+	Sagas      saga.Manager
+}
+
+func (shard *Shard) contains(account uint32) bool {
+	return contains(shard.Min, shard.Max, account)
+}
+
+// Billing instance, that owns range of the accounts
+type Peer struct {
+	Name     string
+	Min, Max uint32 // Range of the accounts (Max = 0 for unlimited)
+	Remote   Remote
+	Timeout  time.Duration // Timeout of the deposit
+}
+
+func contains(min, max, account uint32) bool {
+	return account >= min && (max == 0 || account <= max)
+}
+
+// Map of the accounts to the shards
+type Map func(account uint32) (*Shard, error)
+
+// Map accounts to the shards by ranges
+func RangeMap(shards []*Shard) Map {
+	return func(account uint32) (*Shard, error) {
+		for _, shard := range shards {
+			if shard.contains(account) {
+				return shard, nil
+			}
+		}
+		return nil, domain.ErrShardNotFound
+	}
+}
+
+// Map accounts to the shards by hash of the account
+func HashMap(shards []*Shard) Map {
+	return func(account uint32) (*Shard, error) {
+		if len(shards) == 0 {
+			return nil, domain.ErrShardNotFound
+		}
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(strconv.FormatUint(uint64(account), 10)))
+		return shards[h.Sum32()%uint32(len(shards))], nil
+	}
+}
+
+// Manager routes operations to the shards of the accounts.
+// Transfer between shards or to the peer is performed by saga:
+// amount is withdrawn from the source and kept by the reserved saga,
+// then it is deposited to the destination and the saga is completed
+// or, on rejection, the amount is returned to the source and the saga is compensated.
+// Reserved amount is not a hold, so it can not be committed or rolled back by the clients.
+// Operations with accounts of the peers are rejected.
+type Manager struct {
+	shards []*Shard
+	lookup Map
+	peers  []*Peer
+	age    time.Duration // Age of the sagas, that are resumed
+	logger *slog.Logger
+}
+
+// Get local shard of the account
+func (m *Manager) shard(account uint32) (*Shard, error) {
+	if m.peer(account) != nil {
+		return nil, domain.ErrShardNotFound
+	}
+	return m.lookup(account)
+}
+
+// Get peer of the account (nil for local accounts)
+func (m *Manager) peer(account uint32) *Peer {
+	for _, peer := range m.peers {
+		if contains(peer.Min, peer.Max, account) {
+			return peer
+		}
+	}
+	return nil
+}
+
+func (m *Manager) Credit(
+	ctx context.Context,
+	uid int64,
+	account uint32,
+	amount float32,
+) error {
+	shard, err := m.shard(account)
+	if err != nil {
+		return err
+	}
+	return shard.Banker.Credit(ctx, uid, account, amount)
+}
+
+func (m *Manager) Debit(
+	ctx context.Context,
+	uid int64,
+	account uint32,
+	amount float32,
+) error {
+	shard, err := m.shard(account)
+	if err != nil {
+		return err
+	}
+	return shard.Banker.Debit(ctx, uid, account, amount)
+}
+
+func (m *Manager) Transfer(
+	ctx context.Context,
+	uid int64,
+	src, dst uint32,
+	amount float32,
+) error {
+	source, err := m.shard(src)
+	if err != nil {
+		return err
+	}
+	if m.peer(dst) == nil {
+		target, err := m.lookup(dst)
+		if err != nil {
+			return err
+		}
+		if source == target {
+			return source.Banker.Transfer(ctx, uid, src, dst, amount)
+		}
+	}
+
+	s := &domain.Saga{
+		Uid:    uid,
+		Src:    src,
+		Dst:    dst,
+		Amount: amount,
+		State:  domain.SagaReserved,
+	}
+	err = m.reserve(ctx, source, s)
+	if err != nil {
+		return err
+	}
+	return m.complete(ctx, source, s)
+}
+
+// Receive amount of the transfer from the peer instance
+func (m *Manager) Deposit(
+	ctx context.Context,
+	peer string,
+	uid int64,
+	account uint32,
+	amount float32,
+) error {
+	shard, err := m.shard(account)
+	if err != nil {
+		return err
+	}
+	return shard.Banker.Deposit(ctx, peer, uid, account, amount)
+}
+
+// Hold amount of the account. Beneficiary of the hold must be in the same shard.
+func (m *Manager) Acquire(
+	ctx context.Context,
+	uid int64,
+	account uint32,
+	dst uint32,
+	amount float32,
+) error {
+	shard, err := m.shard(account)
+	if err != nil {
+		return err
+	}
+	if dst != 0 {
+		target, err := m.shard(dst)
+		if err != nil {
+			return err
+		}
+		if target != shard {
+			return domain.ErrForeignBeneficiary
+		}
+	}
+	return shard.Banker.Acquire(ctx, uid, account, dst, amount)
+}
+
+func (m *Manager) Commit(
+	ctx context.Context,
+	uid int64,
+	account uint32,
+) error {
+	shard, err := m.shard(account)
+	if err != nil {
+		return err
+	}
+	return shard.Banker.Commit(ctx, uid, account)
+}
+
+func (m *Manager) Rollback(
+	ctx context.Context,
+	uid int64,
+	account uint32,
+) error {
+	shard, err := m.shard(account)
+	if err != nil {
+		return err
+	}
+	return shard.Banker.Rollback(ctx, uid, account)
+}
+
+// Reverse operation in the shard of the account.
+// Transfers between shards are not reversible.
+func (m *Manager) Reverse(
+	ctx context.Context,
+	reversal *domain.Reversal,
+) error {
+	shard, err := m.shard(reversal.Account)
+	if err != nil {
+		return err
+	}
+	if reversal.Dst != 0 {
+		target, err := m.shard(reversal.Dst)
+		if err != nil {
+			return err
+		}
+		if target != shard {
+			return domain.ErrForeignBeneficiary
+		}
+	}
+	return shard.Banker.Reverse(ctx, reversal)
+}
+
+func (m *Manager) Balance(
+	ctx context.Context,
+	account uint32,
+) (amount float32, err error) {
+	shard, err := m.shard(account)
+	if err != nil {
+		return 0, err
+	}
+	return shard.Banker.Balance(ctx, account)
+}
+
+func (m *Manager) History(
+	ctx context.Context,
+	account uint32,
+	offset, limit int,
+) ([]*domain.HistoryRecord, error) {
+	shard, err := m.shard(account)
+	if err != nil {
+		return nil, err
+	}
+	return shard.Banker.History(ctx, account, offset, limit)
+}
+
+// Open escrow in the shard of the buyer
+func (m *Manager) Open(
+	ctx context.Context,
+	uid int64,
+	id int64,
+	account uint32,
+	amount float32,
+) error {
+	shard, err := m.shard(account)
+	if err != nil {
+		return err
+	}
+	return shard.Escrow.Open(ctx, uid, id, account, amount)
+}
+
+// Release escrow to the sellers. Sellers must be in the shard of the buyer.
+func (m *Manager) Release(
+	ctx context.Context,
+	uid int64,
+	id int64,
+	account uint32,
+	payouts []domain.Payout,
+) error {
+	shard, err := m.shard(account)
+	if err != nil {
+		return err
+	}
+	for _, payout := range payouts {
+		target, err := m.shard(payout.Account)
+		if err != nil {
+			return err
+		}
+		if target != shard {
+			return domain.ErrForeignBeneficiary
+		}
+	}
+	return shard.Escrow.Release(ctx, uid, id, account, payouts)
+}
+
+func (m *Manager) Refund(
+	ctx context.Context,
+	uid int64,
+	id int64,
+	account uint32,
+	amount float32,
+) error {
+	shard, err := m.shard(account)
+	if err != nil {
+		return err
+	}
+	return shard.Escrow.Refund(ctx, uid, id, account, amount)
+}
+
+func (m *Manager) Get(
+	ctx context.Context,
+	id int64,
+	account uint32,
+) (*domain.Escrow, []*domain.EscrowRecord, error) {
+	shard, err := m.shard(account)
+	if err != nil {
+		return nil, nil, err
+	}
+	return shard.Escrow.Get(ctx, id, account)
+}
+
+// Get count and total amount of the active holds of all shards
+func (m *Manager) Summary(
+	ctx context.Context,
+) (count int64, amount float32, err error) {
+	for _, shard := range m.shards {
+		c, a, err := shard.Assets.Summary(ctx)
+		if err != nil {
+			return 0, 0, err
+		}
+		count += c
+		amount += a
+	}
+	return count, amount, nil
+}
+
+// Finish transfers, that are interrupted (e.g. by crash or unavailable destination).
+// Transfers, that are pending longer than the timeout of the deposit, are completed or compensated.
+// Shards are handled one by one.
+func (m *Manager) Resume(ctx context.Context) error {
+	for _, source := range m.shards {
+		sagas, err := source.Sagas.List(ctx, domain.SagaReserved, m.age, resumeLimit)
+		if err != nil {
+			return err
+		}
+		for _, s := range sagas {
+			err := m.complete(ctx, source, s)
+			if err != nil {
+				m.logger.Warn("Transfer is not resumed", "uid", s.Uid, "src", s.Src, "dst", s.Dst, "error", err)
+			}
+		}
+	}
+	return nil
+}
+
+// Resume pending transfers every interval until the context is done
+func (m *Manager) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := m.Resume(ctx)
+		if err != nil {
+			m.logger.Error("Transfers are not resumed", "error", err)
+		}
+	}
+}
+
+// Withdraw amount from the source and register reserved saga in the same transaction
+func (m *Manager) reserve(
+	ctx context.Context,
+	source *Shard,
+	s *domain.Saga,
+) error {
+	return source.Repository.Transaction(
+		ctx,
+		func(ctx context.Context) error {
+			err := source.History.Append(ctx, s.Uid, s.Src, s.Amount, domain.OperationTransferSrc)
+			if err != nil {
+				return err
+			}
+
+			err = source.Accounts.Credit(ctx, s.Src, s.Amount)
+			if err != nil {
+				return err
+			}
+
+			return source.Sagas.Create(ctx, s)
+		},
+	)
+}
+
+// Deposit amount to the destination and mark saga as completed.
+// Rejected deposit is compensated, transient failure leaves saga for Resume.
+func (m *Manager) complete(
+	ctx context.Context,
+	source *Shard,
+	s *domain.Saga,
+) error {
+	err := m.deposit(ctx, s)
+	if err == domain.ErrOperationIsDeprecated {
+		// Amount is deposited already, but the saga is not marked
+		err = nil
+	}
+	if err != nil {
+		if info, ok := domain.DescribeError(err); ok && !info.Retryable {
+			return m.compensate(ctx, source, s, err)
+		}
+		return err
+	}
+
+	err = source.Sagas.Transit(ctx, s.Uid, s.Src, domain.SagaReserved, domain.SagaCompleted)
+	if err == sql.ErrNoRows {
+		// Saga is finished by another worker
+		return nil
+	}
+	return err
+}
+
+// Deposit amount to the local shard or to the peer
+func (m *Manager) deposit(
+	ctx context.Context,
+	s *domain.Saga,
+) error {
+	if peer := m.peer(s.Dst); peer != nil {
+		ctx, cancel := context.WithTimeout(ctx, peer.Timeout)
+		defer cancel()
+		return peer.Remote.Deposit(ctx, s.Uid, s.Dst, s.Amount)
+	}
+
+	target, err := m.lookup(s.Dst)
+	if err != nil {
+		return err
+	}
+
+	return target.Repository.Transaction(
+		ctx,
+		func(ctx context.Context) error {
+			err := target.History.Append(ctx, s.Uid, s.Dst, s.Amount, domain.OperationTransferDst)
+			if err != nil {
+				return err
+			}
+
+			return target.Accounts.Debit(ctx, s.Dst, s.Amount)
+		},
+	)
+}
+
+// Mark saga as compensated and return amount to the source.
+// Transition of the saga goes first, so the amount is returned once, even if the saga is finished concurrently.
+// Returns the cause of the compensation.
+func (m *Manager) compensate(
+	ctx context.Context,
+	source *Shard,
+	s *domain.Saga,
+	cause error,
+) error {
+	err := source.Repository.Transaction(
+		ctx,
+		func(ctx context.Context) error {
+			err := source.Sagas.Transit(ctx, s.Uid, s.Src, domain.SagaReserved, domain.SagaCompensated)
+			if err != nil {
+				return err
+			}
+
+			err = source.History.Append(ctx, s.Uid, s.Src, s.Amount, domain.OperationTransferRefund)
+			if err != nil {
+				return err
+			}
+
+			return source.Accounts.Debit(ctx, s.Src, s.Amount)
+		},
+	)
+	if err == sql.ErrNoRows || err == domain.ErrOperationIsDeprecated {
+		// Saga is finished by another worker
+		return cause
+	}
+	if err != nil {
+		return err
+	}
+
+	m.logger.Warn("Transfer is compensated", "uid", s.Uid, "src", s.Src, "dst", s.Dst, "error", cause)
+	return cause
+}
+
+// Create router of the operations over the shards and the peers.
+// Pending transfers are resumed, when they are older than the age.
+func New(
+	shards []*Shard,
+	lookup Map,
+	peers []*Peer,
+	age time.Duration,
+	logger *slog.Logger,
+) *Manager {
+	return &Manager{
+		shards: shards,
+		lookup: lookup,
+		peers:  peers,
+		age:    age,
+		logger: logger,
+	}
+}
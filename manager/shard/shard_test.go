@@ -0,0 +1,486 @@
+package shard
+
+import (
+	"billing/domain"
+	"billing/manager/banker"
+	"context"
+	"errors"
+	"github.com/adverax/echo/database/sql"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+type repositoryMock struct {
+	sql.Repository
+}
+
+func (r *repositoryMock) Transaction(ctx context.Context, action func(ctx context.Context) error) error {
+	return action(ctx)
+}
+
+type accountsMock struct {
+	balances map[uint32]float32
+	err      error // Failure of the database
+}
+
+func (m *accountsMock) Credit(ctx context.Context, account uint32, amount float32) error {
+	if m.err != nil {
+		return m.err
+	}
+	sum, ok := m.balances[account]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if sum < amount {
+		return domain.ErrNoMoney
+	}
+	m.balances[account] = sum - amount
+	return nil
+}
+
+func (m *accountsMock) Debit(ctx context.Context, account uint32, amount float32) error {
+	if m.err != nil {
+		return m.err
+	}
+	sum, ok := m.balances[account]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	m.balances[account] = sum + amount
+	return nil
+}
+
+func (m *accountsMock) Balance(ctx context.Context, account uint32) (float32, error) {
+	sum, ok := m.balances[account]
+	if !ok {
+		return 0, sql.ErrNoRows
+	}
+	return sum, nil
+}
+
+type historyMock struct {
+	ops      []domain.Operation
+	balances map[uint32]float32 // Accounts, that are referenced by the records
+}
+
+// Record of the missing account is rejected by the foreign key, as the database does
+func (m *historyMock) check(account uint32) error {
+	if m.balances == nil {
+		return nil
+	}
+	if _, ok := m.balances[account]; !ok {
+		return &mysql.MySQLError{Number: 1452, Message: "Cannot add or update a child row: a foreign key constraint fails"}
+	}
+	return nil
+}
+
+func (m *historyMock) Append(ctx context.Context, uid int64, account uint32, amount float32, op domain.Operation) error {
+	if err := m.check(account); err != nil {
+		return err
+	}
+	m.ops = append(m.ops, op)
+	return nil
+}
+
+func (m *historyMock) AppendPeer(ctx context.Context, peer string, uid int64, account uint32, amount float32, op domain.Operation) error {
+	if err := m.check(account); err != nil {
+		return err
+	}
+	m.ops = append(m.ops, op)
+	return nil
+}
+
+func (m *historyMock) List(ctx context.Context, account uint32, offset, limit int) ([]*domain.HistoryRecord, error) {
+	return nil, nil
+}
+
+func (m *historyMock) Lock(ctx context.Context, uid int64, account uint32) ([]*domain.HistoryRecord, error) {
+	return nil, nil
+}
+
+type assetsMock struct {
+	holds map[int64]float32
+}
+
+func (m *assetsMock) Append(ctx context.Context, uid int64, account uint32, dst uint32, amount float32) error {
+	m.holds[uid] = amount
+	return nil
+}
+
+func (m *assetsMock) Remove(ctx context.Context, uid int64, account uint32) (float32, uint32, error) {
+	amount, ok := m.holds[uid]
+	if !ok {
+		return 0, 0, sql.ErrNoRows
+	}
+	delete(m.holds, uid)
+	return amount, 0, nil
+}
+
+func (m *assetsMock) Summary(ctx context.Context) (int64, float32, error) {
+	return int64(len(m.holds)), 0, nil
+}
+
+type remoteMock struct {
+	deposits map[uint32]float32
+	err      error
+}
+
+func (m *remoteMock) Deposit(ctx context.Context, uid int64, account uint32, amount float32) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.deposits[account] += amount
+	return nil
+}
+
+type sagasMock struct {
+	sagas []*domain.Saga
+}
+
+func (m *sagasMock) Create(ctx context.Context, saga *domain.Saga) error {
+	s := *saga
+	m.sagas = append(m.sagas, &s)
+	return nil
+}
+
+func (m *sagasMock) Transit(ctx context.Context, uid int64, src uint32, from, to domain.SagaState) error {
+	for _, s := range m.sagas {
+		if s.Uid == uid && s.Src == src && s.State == from {
+			s.State = to
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (m *sagasMock) List(ctx context.Context, state domain.SagaState, age time.Duration, limit int) (sagas []*domain.Saga, err error) {
+	for _, s := range m.sagas {
+		if s.State == state {
+			sagas = append(sagas, s)
+		}
+	}
+	return sagas, nil
+}
+
+func newShard(name string, min, max uint32, balances map[uint32]float32) *Shard {
+	shard := &Shard{
+		Name:       name,
+		Min:        min,
+		Max:        max,
+		Repository: &repositoryMock{},
+		Accounts:   &accountsMock{balances: balances},
+		History:    &historyMock{balances: balances},
+		Assets:     &assetsMock{holds: make(map[int64]float32)},
+		Sagas:      &sagasMock{},
+	}
+	shard.Banker = banker.NewWithRepository(shard.Repository, shard.Accounts, shard.Assets, shard.History, nil)
+	return shard
+}
+
+func TestRangeMap(t *testing.T) {
+	shards := []*Shard{
+		newShard("first", 1, 100, nil),
+		newShard("second", 101, 0, nil),
+	}
+	lookup := RangeMap(shards)
+
+	tests := map[string]struct {
+		src uint32
+		dst *Shard
+		err error
+	}{
+		"Account must be mapped to the bounded range":   {src: 100, dst: shards[0]},
+		"Account must be mapped to the unbounded range": {src: 5000, dst: shards[1]},
+		"Account out of ranges must be rejected":        {src: 0, err: domain.ErrShardNotFound},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			shard, err := lookup(test.src)
+			require.Equal(t, test.err, err)
+			assert.Equal(t, test.dst, shard)
+		})
+	}
+}
+
+func TestHashMap(t *testing.T) {
+	shards := []*Shard{
+		newShard("first", 0, 0, nil),
+		newShard("second", 0, 0, nil),
+	}
+	lookup := HashMap(shards)
+
+	counts := make(map[string]int)
+	for account := uint32(1); account <= 100; account++ {
+		shard, err := lookup(account)
+		require.NoError(t, err)
+		again, _ := lookup(account)
+		require.Same(t, shard, again)
+		counts[shard.Name]++
+	}
+	assert.NotZero(t, counts["first"])
+	assert.NotZero(t, counts["second"])
+}
+
+func TestManager_Transfer(t *testing.T) {
+	type Dst struct {
+		err      error
+		balances [2]float32
+		state    domain.SagaState
+	}
+
+	type Test struct {
+		src     float32 // Amount of the transfer
+		missing bool    // Destination account does not exist
+		fail    error   // Failure of the destination database
+		dst     Dst
+	}
+
+	tests := map[string]Test{
+		"Transfer between shards must be completed": {
+			src: 30,
+			dst: Dst{
+				balances: [2]float32{70, 30},
+				state:    domain.SagaCompleted,
+			},
+		},
+		"Transfer without money must be rejected": {
+			src: 130,
+			dst: Dst{
+				err:      domain.ErrNoMoney,
+				balances: [2]float32{100, 0},
+			},
+		},
+		"Transfer to the unknown account must be compensated": {
+			src:     30,
+			missing: true,
+			dst: Dst{
+				err:      &mysql.MySQLError{Number: 1452, Message: "Cannot add or update a child row: a foreign key constraint fails"},
+				balances: [2]float32{100, 0},
+				state:    domain.SagaCompensated,
+			},
+		},
+		"Transfer with unavailable destination must be kept for resume": {
+			src:  30,
+			fail: errors.New("connection refused"),
+			dst: Dst{
+				err:      errors.New("connection refused"),
+				balances: [2]float32{70, 0},
+				state:    domain.SagaReserved,
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			first := newShard("first", 1, 100, map[uint32]float32{1: 100})
+			second := newShard("second", 101, 0, map[uint32]float32{101: 0})
+			second.Accounts.(*accountsMock).err = test.fail
+			if test.missing {
+				delete(second.Accounts.(*accountsMock).balances, 101)
+			}
+			m := New([]*Shard{first, second}, RangeMap([]*Shard{first, second}), nil, 0, slog.New(slog.DiscardHandler))
+
+			err := m.Transfer(context.Background(), 1, 1, 101, test.src)
+			require.Equal(t, test.dst.err, err)
+
+			balances := [2]float32{
+				first.Accounts.(*accountsMock).balances[1],
+				second.Accounts.(*accountsMock).balances[101],
+			}
+			assert.Equal(t, test.dst.balances, balances)
+
+			sagas := first.Sagas.(*sagasMock).sagas
+			if test.dst.state == 0 {
+				assert.Empty(t, sagas)
+				return
+			}
+			require.Len(t, sagas, 1)
+			assert.Equal(t, test.dst.state, sagas[0].State)
+
+			count, _, _ := first.Assets.Summary(context.Background())
+			assert.Zero(t, count, "reserved amount must not be held as asset")
+		})
+	}
+}
+
+func TestManager_TransferHold(t *testing.T) {
+	type Test func(m *Manager) error
+
+	tests := map[string]Test{
+		"Rollback of the reserved transfer must be rejected": func(m *Manager) error {
+			return m.Rollback(context.Background(), 1, 1)
+		},
+		"Commit of the reserved transfer must be rejected": func(m *Manager) error {
+			return m.Commit(context.Background(), 1, 1)
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			first := newShard("first", 1, 100, map[uint32]float32{1: 100})
+			second := newShard("second", 101, 0, map[uint32]float32{101: 0})
+			second.Accounts.(*accountsMock).err = errors.New("connection refused")
+			m := New([]*Shard{first, second}, RangeMap([]*Shard{first, second}), nil, 0, slog.New(slog.DiscardHandler))
+
+			err := m.Transfer(context.Background(), 1, 1, 101, 30)
+			require.Error(t, err)
+
+			err = test(m)
+			assert.Equal(t, sql.ErrNoRows, err)
+			assert.Equal(t, float32(70), first.Accounts.(*accountsMock).balances[1])
+
+			second.Accounts.(*accountsMock).err = nil
+			err = m.Resume(context.Background())
+			require.NoError(t, err)
+
+			assert.Equal(t, float32(70), first.Accounts.(*accountsMock).balances[1])
+			assert.Equal(t, float32(30), second.Accounts.(*accountsMock).balances[101])
+			assert.Equal(t, domain.SagaCompleted, first.Sagas.(*sagasMock).sagas[0].State)
+		})
+	}
+}
+
+func TestManager_Resume(t *testing.T) {
+	first := newShard("first", 1, 100, map[uint32]float32{1: 70})
+	second := newShard("second", 101, 0, map[uint32]float32{101: 0})
+	first.Sagas.(*sagasMock).sagas = []*domain.Saga{
+		{Uid: 1, Src: 1, Dst: 101, Amount: 30, State: domain.SagaReserved},
+	}
+	m := New([]*Shard{first, second}, RangeMap([]*Shard{first, second}), nil, 0, slog.New(slog.DiscardHandler))
+
+	err := m.Resume(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, float32(30), second.Accounts.(*accountsMock).balances[101])
+	assert.Equal(t, domain.SagaCompleted, first.Sagas.(*sagasMock).sagas[0].State)
+	assert.Equal(t, []domain.Operation{domain.OperationTransferDst}, second.History.(*historyMock).ops)
+}
+
+func TestManager_TransferToPeer(t *testing.T) {
+	type Dst struct {
+		err      error
+		balance  float32
+		deposits map[uint32]float32
+		state    domain.SagaState
+	}
+
+	type Test struct {
+		src error // Failure of the peer
+		dst Dst
+	}
+
+	tests := map[string]Test{
+		"Transfer to the peer must be completed": {
+			dst: Dst{
+				balance:  70,
+				deposits: map[uint32]float32{2001: 30},
+				state:    domain.SagaCompleted,
+			},
+		},
+		"Repeated deposit must complete transfer": {
+			src: domain.ErrOperationIsDeprecated,
+			dst: Dst{
+				balance:  70,
+				deposits: map[uint32]float32{},
+				state:    domain.SagaCompleted,
+			},
+		},
+		"Rejected deposit must be compensated": {
+			src: &domain.RemoteError{Code: domain.ErrorCodeNotFound},
+			dst: Dst{
+				err:      &domain.RemoteError{Code: domain.ErrorCodeNotFound},
+				balance:  100,
+				deposits: map[uint32]float32{},
+				state:    domain.SagaCompensated,
+			},
+		},
+		"Timed out deposit must be kept for resume": {
+			src: context.DeadlineExceeded,
+			dst: Dst{
+				err:      context.DeadlineExceeded,
+				balance:  70,
+				deposits: map[uint32]float32{},
+				state:    domain.SagaReserved,
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			local := newShard("local", 1, 1000, map[uint32]float32{1: 100})
+			remote := &remoteMock{deposits: make(map[uint32]float32), err: test.src}
+			peers := []*Peer{{Name: "peer", Min: 2001, Remote: remote, Timeout: time.Second}}
+			m := New([]*Shard{local}, RangeMap([]*Shard{local}), peers, 0, slog.New(slog.DiscardHandler))
+
+			err := m.Transfer(context.Background(), 1, 1, 2001, 30)
+			require.Equal(t, test.dst.err, err)
+
+			assert.Equal(t, test.dst.balance, local.Accounts.(*accountsMock).balances[1])
+			assert.Equal(t, test.dst.deposits, remote.deposits)
+			sagas := local.Sagas.(*sagasMock).sagas
+			require.Len(t, sagas, 1)
+			assert.Equal(t, test.dst.state, sagas[0].State)
+		})
+	}
+}
+
+func TestManager_PeerAccount(t *testing.T) {
+	local := newShard("local", 0, 0, map[uint32]float32{1: 100})
+	peers := []*Peer{{Name: "peer", Min: 2001, Max: 3000}}
+	m := New([]*Shard{local}, RangeMap([]*Shard{local}), peers, 0, slog.New(slog.DiscardHandler))
+
+	_, err := m.Balance(context.Background(), 2001)
+	assert.Equal(t, domain.ErrShardNotFound, err)
+
+	err = m.Transfer(context.Background(), 1, 2001, 1, 10)
+	assert.Equal(t, domain.ErrShardNotFound, err)
+
+	err = m.Acquire(context.Background(), 1, 1, 2001, 10)
+	assert.Equal(t, domain.ErrShardNotFound, err)
+
+	err = m.Deposit(context.Background(), "peer", 1, 2001, 10)
+	assert.Equal(t, domain.ErrShardNotFound, err)
+}
+
+func TestManager_Deposit(t *testing.T) {
+	first := newShard("first", 1, 100, map[uint32]float32{1: 100})
+	second := newShard("second", 101, 0, map[uint32]float32{101: 0})
+	m := New([]*Shard{first, second}, RangeMap([]*Shard{first, second}), nil, 0, slog.New(slog.DiscardHandler))
+
+	err := m.Deposit(context.Background(), "peer", 1, 101, 30)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[uint32]float32{101: 30}, second.Accounts.(*accountsMock).balances)
+	assert.Equal(t, []domain.Operation{domain.OperationTransferDst}, second.History.(*historyMock).ops)
+	assert.Empty(t, first.History.(*historyMock).ops)
+
+	err = m.Deposit(context.Background(), "peer", 2, 102, 30)
+	require.Equal(t, sql.ErrNoRows, err, "deposit to the missing account must be rejected")
+	info, known := domain.DescribeError(err)
+	assert.True(t, known && !info.Retryable, "peer must compensate the rejected deposit")
+	assert.Len(t, second.History.(*historyMock).ops, 1)
+}
+
+func TestManager_Acquire(t *testing.T) {
+	first := newShard("first", 1, 100, map[uint32]float32{1: 100})
+	second := newShard("second", 101, 0, nil)
+	m := New([]*Shard{first, second}, RangeMap([]*Shard{first, second}), nil, 0, slog.New(slog.DiscardHandler))
+
+	err := m.Acquire(context.Background(), 1, 1, 101, 10)
+	assert.Equal(t, domain.ErrForeignBeneficiary, err)
+
+	err = m.Acquire(context.Background(), 1, 1, 2, 10)
+	assert.Equal(t, sql.ErrNoRows, err, "missing beneficiary must be rejected")
+	assert.Equal(t, float32(100), first.Accounts.(*accountsMock).balances[1])
+
+	err = m.Release(context.Background(), 1, 1, 1, []domain.Payout{{Account: 2, Amount: 5}, {Account: 101, Amount: 5}})
+	assert.Equal(t, domain.ErrForeignBeneficiary, err)
+
+	err = m.Reverse(context.Background(), &domain.Reversal{Uid: 2, Original: 1, Account: 1, Dst: 101})
+	assert.Equal(t, domain.ErrForeignBeneficiary, err)
+}
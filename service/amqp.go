@@ -0,0 +1,298 @@
+package service
+
+import (
+	"billing/domain"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/streadway/amqp"
+	"log/slog"
+	"sync"
+)
+
+const amqpContentType = "application/json"
+
+type amqpPublisher interface {
+	Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error
+}
+
+type amqpMessage struct {
+	transport *amqpTransport
+	delivery  amqp.Delivery
+	settled   bool
+}
+
+func (m *amqpMessage) Subject() string {
+	return m.delivery.RoutingKey
+}
+
+func (m *amqpMessage) Data() []byte {
+	return m.delivery.Body
+}
+
+func (m *amqpMessage) Reply(data []byte) error {
+	return m.ReplyWithHeaders(data, nil)
+}
+
+// Get headers of the message with string values
+func (m *amqpMessage) Headers() map[string]string {
+	headers := make(map[string]string, len(m.delivery.Headers))
+	for key, value := range m.delivery.Headers {
+		switch v := value.(type) {
+		case string:
+			headers[key] = v
+		case []byte:
+			headers[key] = string(v)
+		}
+	}
+	return headers
+}
+
+// Reply to the reply_to queue with correlation_id of the request and acknowledge the request.
+func (m *amqpMessage) ReplyWithHeaders(data []byte, headers map[string]string) error {
+	var err error
+	if m.delivery.ReplyTo != "" {
+		err = m.transport.publish("", m.delivery.ReplyTo, amqp.Publishing{
+			Headers:       amqpHeaders(headers),
+			ContentType:   amqpContentType,
+			CorrelationId: m.delivery.CorrelationId,
+			Body:          data,
+		})
+	}
+
+	if !m.settled {
+		m.settled = true
+		e := m.delivery.Ack(false)
+		if err == nil {
+			err = e
+		}
+	}
+
+	return err
+}
+
+// Return message to the queue once.
+// Message, that fails again, is poison: it is rejected to the dead letter exchange.
+func (m *amqpMessage) Requeue() bool {
+	if m.settled {
+		return false
+	}
+	m.settled = true
+
+	if !m.delivery.Redelivered {
+		err := m.delivery.Nack(false, true)
+		if err != nil {
+			m.transport.logger.Error("AMQP message is not settled", "subject", m.Subject(), "error", err)
+		}
+		return true
+	}
+
+	err := m.delivery.Reject(false)
+	if err != nil {
+		m.transport.logger.Error("AMQP message is not settled", "subject", m.Subject(), "error", err)
+	}
+	return false
+}
+
+type amqpTransport struct {
+	options    domain.AmqpOptions
+	deadLetter string
+	conn       *amqp.Connection
+	channel    *amqp.Channel
+	publisher  amqpPublisher
+	consumers  []string
+	mu         sync.Mutex
+	wg         sync.WaitGroup
+	logger     *slog.Logger
+}
+
+// Declare durable queue (named by queue group) bound to the exchange by subject and consume it.
+func (t *amqpTransport) Subscribe(
+	subject, queue string,
+	handler Handler,
+) error {
+	args := amqp.Table{}
+	if t.deadLetter != "" {
+		args["x-dead-letter-exchange"] = t.options.DeadLetterExchange
+	}
+
+	_, err := t.channel.QueueDeclare(queue, true, false, false, false, args)
+	if err != nil {
+		return err
+	}
+
+	err = t.channel.QueueBind(queue, subject, t.options.Exchange, false, nil)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	consumer := fmt.Sprintf("%s#%d", queue, len(t.consumers))
+	t.consumers = append(t.consumers, consumer)
+	t.mu.Unlock()
+
+	deliveries, err := t.channel.Consume(queue, consumer, false, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		for delivery := range deliveries {
+			handler(&amqpMessage{transport: t, delivery: delivery})
+		}
+	}()
+
+	return nil
+}
+
+func (t *amqpTransport) Publish(subject string, data []byte) error {
+	return t.PublishWithHeaders(subject, data, nil)
+}
+
+func (t *amqpTransport) PublishWithHeaders(subject string, data []byte, headers map[string]string) error {
+	return t.publish(t.options.Exchange, subject, amqp.Publishing{
+		Headers:      amqpHeaders(headers),
+		ContentType:  amqpContentType,
+		DeliveryMode: amqp.Persistent,
+		Body:         data,
+	})
+}
+
+// Cancel consumers and wait for the handlers.
+// Prefetched messages, that are not handled, are returned to the queues by the server.
+func (t *amqpTransport) Drain() error {
+	t.mu.Lock()
+	consumers := t.consumers
+	t.consumers = nil
+	t.mu.Unlock()
+
+	for _, consumer := range consumers {
+		err := t.channel.Cancel(consumer, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	t.wg.Wait()
+	return nil
+}
+
+func (t *amqpTransport) Check(ctx context.Context) error {
+	if t.conn.IsClosed() {
+		return errors.New("AMQP connection is closed")
+	}
+	return nil
+}
+
+func (t *amqpTransport) Close() error {
+	err := t.channel.Close()
+	if e := t.conn.Close(); err == nil {
+		err = e
+	}
+	t.wg.Wait()
+	return err
+}
+
+func (t *amqpTransport) publish(
+	exchange, key string,
+	msg amqp.Publishing,
+) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.publisher.Publish(exchange, key, false, false, msg)
+}
+
+func amqpHeaders(headers map[string]string) amqp.Table {
+	if len(headers) == 0 {
+		return nil
+	}
+
+	table := make(amqp.Table, len(headers))
+	for key, value := range headers {
+		table[key] = value
+	}
+	return table
+}
+
+// Declare exchanges and queue for dead letters
+func (t *amqpTransport) setup() error {
+	err := t.channel.Qos(t.options.Prefetch, 0, false)
+	if err != nil {
+		return err
+	}
+
+	err = t.channel.ExchangeDeclare(t.options.Exchange, "direct", true, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+
+	if t.deadLetter == "" {
+		return nil
+	}
+
+	err = t.channel.ExchangeDeclare(t.options.DeadLetterExchange, "fanout", true, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = t.channel.QueueDeclare(t.deadLetter, true, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+
+	// Rejected poison messages
+	err = t.channel.QueueBind(t.deadLetter, "", t.options.DeadLetterExchange, false, nil)
+	if err != nil {
+		return err
+	}
+
+	// Dead letters, published by the service
+	return t.channel.QueueBind(t.deadLetter, t.deadLetter, t.options.Exchange, false, nil)
+}
+
+// Create transport over the AMQP server.
+// Commands are published into the exchange with subject as routing key.
+// Responses are sent to the reply_to queue with correlation_id of the request.
+// Messages are acknowledged only after the response is sent.
+func NewAmqpTransport(
+	options domain.BrokerOptions,
+	logger *slog.Logger,
+) (Transport, error) {
+	conn, err := amqp.Dial(options.Amqp.Url)
+	if err != nil {
+		return nil, err
+	}
+
+	channel, err := conn.Channel()
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	t := &amqpTransport{
+		options:   options.Amqp,
+		conn:      conn,
+		channel:   channel,
+		publisher: channel,
+		logger:    logger,
+	}
+	if options.Amqp.DeadLetterExchange != "" {
+		t.deadLetter = options.DeadLetter
+	}
+
+	err = t.setup()
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	go func() {
+		for e := range conn.NotifyClose(make(chan *amqp.Error, 1)) {
+			logger.Error("AMQP connection is closed", "error", e)
+		}
+	}()
+
+	return t, nil
+}
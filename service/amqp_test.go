@@ -0,0 +1,153 @@
+package service
+
+import (
+	"billing/domain"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"testing"
+)
+
+type amqpJournal struct {
+	events   []string
+	messages []amqp.Publishing
+}
+
+func (j *amqpJournal) Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+	j.events = append(j.events, "publish "+exchange+" "+key)
+	j.messages = append(j.messages, msg)
+	return nil
+}
+
+func (j *amqpJournal) Ack(tag uint64, multiple bool) error {
+	j.events = append(j.events, "ack")
+	return nil
+}
+
+func (j *amqpJournal) Nack(tag uint64, multiple bool, requeue bool) error {
+	if requeue {
+		j.events = append(j.events, "nack requeue")
+	} else {
+		j.events = append(j.events, "nack")
+	}
+	return nil
+}
+
+func (j *amqpJournal) Reject(tag uint64, requeue bool) error {
+	j.events = append(j.events, "reject")
+	return nil
+}
+
+func TestAmqpMessage(t *testing.T) {
+	type Src struct {
+		err         error
+		redelivered bool
+	}
+
+	type Dst struct {
+		events []string
+		status uint8
+	}
+
+	type Test struct {
+		src Src
+		dst Dst
+	}
+
+	tests := map[string]Test{
+		"Success": {
+			src: Src{},
+			dst: Dst{
+				events: []string{"publish  reply", "ack"},
+				status: domain.StatusOk,
+			},
+		},
+		"Business error": {
+			src: Src{
+				err: domain.ErrNoMoney,
+			},
+			dst: Dst{
+				events: []string{"publish  reply", "ack"},
+				status: domain.StatusNoMoney,
+			},
+		},
+		"Transient error": {
+			src: Src{
+				err: driver.ErrBadConn,
+			},
+			dst: Dst{
+				events: []string{"nack requeue"},
+			},
+		},
+		"Transient error of redelivered message": {
+			src: Src{
+				err:         driver.ErrBadConn,
+				redelivered: true,
+			},
+			dst: Dst{
+				events: []string{"reject", "publish  reply"},
+				status: domain.StatusUnknownError,
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			journal := &amqpJournal{}
+			transport := &amqpTransport{
+				options:   domain.AmqpOptions{Exchange: "bank"},
+				publisher: journal,
+				logger:    slog.New(slog.DiscardHandler),
+			}
+			manager := &managerMock{err: test.src.err}
+			s := &server{
+				transport: transport,
+				manager:   manager,
+				logger:    slog.New(slog.DiscardHandler),
+			}
+
+			msg := &amqpMessage{
+				transport: transport,
+				delivery: amqp.Delivery{
+					Acknowledger:  journal,
+					CorrelationId: "c1",
+					ReplyTo:       "reply",
+					Redelivered:   test.src.redelivered,
+					RoutingKey:    "bank.credit",
+					Body:          []byte(`{"uid":1,"account":7,"amount":10}`),
+				},
+			}
+			s.handle(context.Background(), endpoints(manager)["credit"], msg)
+
+			assert.Equal(t, test.dst.events, journal.events)
+			assert.Equal(t, []string{"credit 1 7 10"}, manager.calls)
+			if len(journal.messages) != 0 {
+				require.Len(t, journal.messages, 1)
+				assert.Equal(t, "c1", journal.messages[0].CorrelationId)
+				var response Response
+				require.NoError(t, json.Unmarshal(journal.messages[0].Body, &response))
+				assert.Equal(t, test.dst.status, response.Status)
+			}
+		})
+	}
+}
+
+func TestAmqpTransport_Publish(t *testing.T) {
+	journal := &amqpJournal{}
+	transport := &amqpTransport{
+		options:   domain.AmqpOptions{Exchange: "bank"},
+		publisher: journal,
+		logger:    slog.New(slog.DiscardHandler),
+	}
+
+	err := transport.Publish("bank.dead", []byte("letter"))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"publish bank bank.dead"}, journal.events)
+	assert.Equal(t, amqp.Persistent, journal.messages[0].DeliveryMode)
+	assert.Equal(t, "letter", string(journal.messages[0].Body))
+}
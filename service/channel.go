@@ -0,0 +1,177 @@
+package service
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	ErrTransportClosed = errors.New("transport is closed")
+	ErrRequestTimeout  = errors.New("request timeout")
+)
+
+type channelMessage struct {
+	transport *ChannelTransport
+	subject   string
+	reply     string
+	data      []byte
+}
+
+func (m *channelMessage) Subject() string {
+	return m.subject
+}
+
+func (m *channelMessage) Data() []byte {
+	return m.data
+}
+
+func (m *channelMessage) Reply(data []byte) error {
+	if m.reply == "" {
+		return nil
+	}
+	return m.transport.publish(m.reply, "", data)
+}
+
+type channelGroup struct {
+	handlers []Handler
+	next     int
+}
+
+// In-process transport based on channels and goroutines.
+// Useful for tests and embedding the service into another application.
+type ChannelTransport struct {
+	mu      sync.Mutex
+	groups  map[string]map[string]*channelGroup // subject -> queue -> group
+	inboxes map[string]chan []byte
+	inbox   uint64
+	closed  bool
+	active  sync.WaitGroup
+}
+
+func (t *ChannelTransport) Subscribe(
+	subject, queue string,
+	handler Handler,
+) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return ErrTransportClosed
+	}
+
+	groups, ok := t.groups[subject]
+	if !ok {
+		groups = make(map[string]*channelGroup)
+		t.groups[subject] = groups
+	}
+
+	group, ok := groups[queue]
+	if !ok {
+		group = new(channelGroup)
+		groups[queue] = group
+	}
+
+	group.handlers = append(group.handlers, handler)
+	return nil
+}
+
+func (t *ChannelTransport) Publish(subject string, data []byte) error {
+	return t.publish(subject, "", data)
+}
+
+// Send request and wait for the response
+func (t *ChannelTransport) Request(
+	subject string,
+	data []byte,
+	timeout time.Duration,
+) ([]byte, error) {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return nil, ErrTransportClosed
+	}
+	t.inbox++
+	reply := "_INBOX." + strconv.FormatUint(t.inbox, 10)
+	inbox := make(chan []byte, 1)
+	t.inboxes[reply] = inbox
+	t.mu.Unlock()
+
+	defer func() {
+		t.mu.Lock()
+		delete(t.inboxes, reply)
+		t.mu.Unlock()
+	}()
+
+	err := t.publish(subject, reply, data)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case response := <-inbox:
+		return response, nil
+	case <-time.After(timeout):
+		return nil, ErrRequestTimeout
+	}
+}
+
+// Remove all subscriptions and wait for the active handlers
+func (t *ChannelTransport) Drain() error {
+	t.mu.Lock()
+	t.groups = make(map[string]map[string]*channelGroup)
+	t.mu.Unlock()
+
+	t.active.Wait()
+	return nil
+}
+
+func (t *ChannelTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closed = true
+	return nil
+}
+
+func (t *ChannelTransport) publish(subject, reply string, data []byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return ErrTransportClosed
+	}
+
+	if inbox, ok := t.inboxes[subject]; ok {
+		select {
+		case inbox <- data:
+		default:
+		}
+		return nil
+	}
+
+	for _, group := range t.groups[subject] {
+		handler := group.handlers[group.next%len(group.handlers)]
+		group.next++
+		msg := &channelMessage{
+			transport: t,
+			subject:   subject,
+			reply:     reply,
+			data:      data,
+		}
+		t.active.Add(1)
+		go func() {
+			defer t.active.Done()
+			handler(msg)
+		}()
+	}
+
+	return nil
+}
+
+func NewChannelTransport() *ChannelTransport {
+	return &ChannelTransport{
+		groups:  make(map[string]map[string]*channelGroup),
+		inboxes: make(map[string]chan []byte),
+	}
+}
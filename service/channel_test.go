@@ -0,0 +1,234 @@
+package service
+
+import (
+	"billing/domain"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestChannelTransport_Request(t *testing.T) {
+	transport := NewChannelTransport()
+	defer transport.Close()
+
+	err := transport.Subscribe("echo", "echo", func(msg Message) {
+		_ = msg.Reply(append([]byte(msg.Subject()+":"), msg.Data()...))
+	})
+	require.NoError(t, err)
+
+	response, err := transport.Request("echo", []byte("ping"), time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "echo:ping", string(response))
+
+	_, err = transport.Request("nobody", []byte("ping"), 10*time.Millisecond)
+	assert.Equal(t, ErrRequestTimeout, err)
+}
+
+func TestChannelTransport_QueueGroup(t *testing.T) {
+	transport := NewChannelTransport()
+	defer transport.Close()
+
+	received := make(chan string, 10)
+	for _, name := range []string{"a", "b"} {
+		name := name
+		err := transport.Subscribe("work", "workers", func(msg Message) {
+			received <- name
+		})
+		require.NoError(t, err)
+	}
+
+	for i := 0; i < 4; i++ {
+		require.NoError(t, transport.Publish("work", nil))
+	}
+
+	counts := map[string]int{}
+	for i := 0; i < 4; i++ {
+		select {
+		case name := <-received:
+			counts[name]++
+		case <-time.After(time.Second):
+			t.Fatal("message is not delivered")
+		}
+	}
+	assert.Equal(t, map[string]int{"a": 2, "b": 2}, counts)
+}
+
+func TestSubscribe(t *testing.T) {
+	transport := NewChannelTransport()
+	defer transport.Close()
+
+	letters := make(chan DeadLetter, 1)
+	err := transport.Subscribe("bank.dead", "test", func(msg Message) {
+		var letter DeadLetter
+		_ = json.Unmarshal(msg.Data(), &letter)
+		letters <- letter
+	})
+	require.NoError(t, err)
+
+	manager := &managerMock{}
+	_, err = Subscribe(
+		context.Background(),
+		transport,
+		manager,
+		&managerMock{},
+		domain.BrokerOptions{DeadLetter: "bank.dead", Prefix: "bank."},
+		slog.New(slog.DiscardHandler),
+	)
+	require.NoError(t, err)
+
+	data, err := transport.Request("bank.debit", []byte(`{"uid":1,"account":2,"amount":3}`), time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, `{"Status":0}`, string(data))
+	assert.Equal(t, []string{"debit 1 2 3"}, manager.calls)
+
+	data, err = transport.Request("bank.credit", []byte(`{"uid":`), time.Second)
+	require.NoError(t, err)
+	var response Response
+	require.NoError(t, json.Unmarshal(data, &response))
+	assert.Equal(t, uint8(domain.StatusInvalidRequest), response.Status)
+
+	select {
+	case letter := <-letters:
+		assert.Equal(t, "bank.credit", letter.Subject)
+		assert.Equal(t, `{"uid":`, string(letter.Data))
+		assert.Equal(t, "malformed_request", letter.Reason)
+	case <-time.After(time.Second):
+		t.Fatal("dead letter is not published")
+	}
+}
+
+type blockingManager struct {
+	managerMock
+	started chan struct{}
+	release chan struct{}
+}
+
+func (m *blockingManager) Debit(ctx context.Context, uid int64, account uint32, amount float32) error {
+	m.started <- struct{}{}
+	select {
+	case <-m.release:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestServer_Drain(t *testing.T) {
+	const count = 5
+
+	transport := NewChannelTransport()
+	defer transport.Close()
+
+	manager := &blockingManager{
+		started: make(chan struct{}, count),
+		release: make(chan struct{}),
+	}
+	subscription, err := Subscribe(
+		context.Background(),
+		transport,
+		manager,
+		&managerMock{},
+		domain.BrokerOptions{Prefix: "bank."},
+		slog.New(slog.DiscardHandler),
+	)
+	require.NoError(t, err)
+
+	replies := make(chan string, count)
+	var requesters sync.WaitGroup
+	for i := 0; i < count; i++ {
+		requesters.Add(1)
+		go func() {
+			defer requesters.Done()
+			data, err := transport.Request("bank.debit", []byte(`{"uid":1,"account":2,"amount":3}`), 5*time.Second)
+			if err != nil {
+				replies <- err.Error()
+				return
+			}
+			replies <- string(data)
+		}()
+	}
+	for i := 0; i < count; i++ {
+		<-manager.started
+	}
+
+	drained := make(chan error, 1)
+	go func() {
+		drained <- subscription.Drain()
+	}()
+
+	select {
+	case <-drained:
+		t.Fatal("drain must wait for the operations in progress")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(manager.release)
+
+	select {
+	case err := <-drained:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("drain is not completed")
+	}
+
+	// All accepted requests are replied
+	requesters.Wait()
+	require.Len(t, replies, count)
+	for i := 0; i < count; i++ {
+		assert.Equal(t, `{"Status":0}`, <-replies)
+	}
+
+	// New requests are not accepted
+	_, err = transport.Request("bank.debit", []byte(`{"uid":2,"account":2,"amount":3}`), 50*time.Millisecond)
+	assert.Equal(t, ErrRequestTimeout, err)
+	assert.Len(t, manager.started, 0)
+}
+
+func TestServer_Overload(t *testing.T) {
+	transport := NewChannelTransport()
+	defer transport.Close()
+
+	manager := &blockingManager{
+		started: make(chan struct{}, 1),
+		release: make(chan struct{}),
+	}
+	subscription, err := Subscribe(
+		context.Background(),
+		transport,
+		manager,
+		&managerMock{},
+		domain.BrokerOptions{
+			Prefix: "bank.",
+			Endpoints: map[string]domain.EndpointOptions{
+				"debit": {Workers: 1},
+			},
+		},
+		slog.New(slog.DiscardHandler),
+	)
+	require.NoError(t, err)
+
+	first := make(chan string, 1)
+	go func() {
+		data, _ := transport.Request("bank.debit", []byte(`{"uid":1,"account":2,"amount":3}`), 5*time.Second)
+		first <- string(data)
+	}()
+	<-manager.started
+
+	data, err := transport.Request("bank.debit", []byte(`{"uid":2,"account":2,"amount":3}`), time.Second)
+	require.NoError(t, err)
+	var response Response
+	require.NoError(t, json.Unmarshal(data, &response))
+	require.NotNil(t, response.Error)
+	assert.Equal(t, domain.ErrorCodeOverloaded, response.Error.Code)
+	assert.Equal(t, int64(2), response.Error.Uid)
+	assert.True(t, response.Error.Retryable)
+
+	close(manager.release)
+	assert.Equal(t, `{"Status":0}`, <-first)
+	require.NoError(t, subscription.Drain())
+}
@@ -1,60 +1,368 @@
 package service
 
-type CreditRequest struct {
-	Uid     int64
-	Account uint32
-	Amount  float32
+import (
+	"billing/domain"
+	"math"
+	"time"
+)
+
+// Error payload of the failed operation
+type Error struct {
+	Code          string // Stable machine-readable code (see domain.ErrorCode*)
+	Message       string // Human-readable message
+	Retryable     bool   // Operation can be repeated with the same uid
+	Uid           int64  // Uid of the failed operation
+	CorrelationId string `json:",omitempty"` // Correlation id of the request
+}
+
+// Incoming request of any operation
+type Request interface {
+	Validate() error
+	Header() Header
+}
+
+// Attributes common for all requests
+type Header struct {
+	Uid           int64
+	CorrelationId string
+	Deadline      time.Time // Deadline of the operation set by the caller (zero for none)
 }
 
-type CreditResponse struct {
+type Response struct {
 	Status uint8
+	Error  *Error `json:",omitempty"`
+}
+
+type CreditRequest struct {
+	Uid           int64
+	Account       uint32
+	Amount        float32
+	CorrelationId string
+	Deadline      time.Time
+}
+
+func (r *CreditRequest) Validate() error {
+	return validate(
+		validateUid(r.Uid),
+		validateAccount(r.Account),
+		validateAmount(r.Amount),
+	)
+}
+
+func (r *CreditRequest) Header() Header {
+	return Header{Uid: r.Uid, CorrelationId: r.CorrelationId, Deadline: r.Deadline}
 }
 
 type DebitRequest struct {
-	Uid     int64
-	Account uint32
-	Amount  float32
+	Uid           int64
+	Account       uint32
+	Amount        float32
+	CorrelationId string
+	Deadline      time.Time
 }
 
-type DebitResponse struct {
-	Status uint8
+func (r *DebitRequest) Validate() error {
+	return validate(
+		validateUid(r.Uid),
+		validateAccount(r.Account),
+		validateAmount(r.Amount),
+	)
+}
+
+func (r *DebitRequest) Header() Header {
+	return Header{Uid: r.Uid, CorrelationId: r.CorrelationId, Deadline: r.Deadline}
 }
 
 type TransferRequest struct {
-	Uid    int64
-	Src    uint32
-	Dst    uint32
-	Amount float32
+	Uid           int64
+	Src           uint32
+	Dst           uint32
+	Amount        float32
+	CorrelationId string
+	Deadline      time.Time
 }
 
-type TransferResponse struct {
-	Status uint8
+func (r *TransferRequest) Validate() error {
+	err := validate(
+		validateUid(r.Uid),
+		validateAccount(r.Src),
+		validateAccount(r.Dst),
+		validateAmount(r.Amount),
+	)
+	if err != nil {
+		return err
+	}
+	if r.Src == r.Dst {
+		return domain.ErrSameAccount
+	}
+	return nil
+}
+
+func (r *TransferRequest) Header() Header {
+	return Header{Uid: r.Uid, CorrelationId: r.CorrelationId, Deadline: r.Deadline}
+}
+
+// Transfer from the peer instance
+type DepositRequest struct {
+	Uid           int64  // Uid of the transfer in the peer
+	Peer          string // Name of the sending instance
+	Account       uint32
+	Amount        float32
+	CorrelationId string
+	Deadline      time.Time
+}
+
+func (r *DepositRequest) Validate() error {
+	return validate(
+		validateUid(r.Uid),
+		validatePeer(r.Peer),
+		validateAccount(r.Account),
+		validateAmount(r.Amount),
+	)
+}
+
+func (r *DepositRequest) Header() Header {
+	return Header{Uid: r.Uid, CorrelationId: r.CorrelationId, Deadline: r.Deadline}
 }
 
 type AcquireRequest struct {
-	Uid     int64
-	Account uint32
-	Amount  float32
+	Uid           int64
+	Account       uint32
+	Dst           uint32 // Beneficiary of the hold (0 for none)
+	Amount        float32
+	CorrelationId string
+	Deadline      time.Time
 }
 
-type AcquireResponse struct {
-	Status uint8
+func (r *AcquireRequest) Validate() error {
+	err := validate(
+		validateUid(r.Uid),
+		validateAccount(r.Account),
+		validateAmount(r.Amount),
+	)
+	if err != nil {
+		return err
+	}
+	if r.Dst == r.Account {
+		return domain.ErrSameAccount
+	}
+	return nil
+}
+
+func (r *AcquireRequest) Header() Header {
+	return Header{Uid: r.Uid, CorrelationId: r.CorrelationId, Deadline: r.Deadline}
 }
 
 type CommitRequest struct {
-	Uid     int64
-	Account uint32
+	Uid           int64
+	Account       uint32
+	CorrelationId string
+	Deadline      time.Time
 }
 
-type CommitResponse struct {
-	Status uint8
+func (r *CommitRequest) Validate() error {
+	return validate(
+		validateUid(r.Uid),
+		validateAccount(r.Account),
+	)
+}
+
+func (r *CommitRequest) Header() Header {
+	return Header{Uid: r.Uid, CorrelationId: r.CorrelationId, Deadline: r.Deadline}
 }
 
 type RollbackRequest struct {
-	Uid     int64
-	Account uint32
+	Uid           int64
+	Account       uint32
+	CorrelationId string
+	Deadline      time.Time
 }
 
-type RollbackResponse struct {
-	Status uint8
+func (r *RollbackRequest) Validate() error {
+	return validate(
+		validateUid(r.Uid),
+		validateAccount(r.Account),
+	)
+}
+
+func (r *RollbackRequest) Header() Header {
+	return Header{Uid: r.Uid, CorrelationId: r.CorrelationId, Deadline: r.Deadline}
+}
+
+type ReverseRequest struct {
+	Uid           int64
+	Original      int64   // Uid of the reversed operation
+	Account       uint32  // Account of the original operation (source of the transfer)
+	Dst           uint32  // Recipient of the reversed transfer (0 for Credit and Debit)
+	Amount        float32 // Reversed amount (0 for the whole rest of the original)
+	CorrelationId string
+	Deadline      time.Time
+}
+
+func (r *ReverseRequest) Validate() error {
+	err := validate(
+		validateUid(r.Uid),
+		validateOriginal(r.Original),
+		validateAccount(r.Account),
+	)
+	if err != nil {
+		return err
+	}
+	if r.Amount != 0 {
+		err := validateAmount(r.Amount)
+		if err != nil {
+			return err
+		}
+	}
+	if r.Dst == r.Account {
+		return domain.ErrSameAccount
+	}
+	return nil
+}
+
+func (r *ReverseRequest) Header() Header {
+	return Header{Uid: r.Uid, CorrelationId: r.CorrelationId, Deadline: r.Deadline}
+}
+
+type EscrowOpenRequest struct {
+	Uid           int64
+	Escrow        int64
+	Account       uint32 // Account of the buyer
+	Amount        float32
+	CorrelationId string
+	Deadline      time.Time
+}
+
+func (r *EscrowOpenRequest) Validate() error {
+	return validate(
+		validateUid(r.Uid),
+		validateEscrow(r.Escrow),
+		validateAccount(r.Account),
+		validateAmount(r.Amount),
+	)
+}
+
+func (r *EscrowOpenRequest) Header() Header {
+	return Header{Uid: r.Uid, CorrelationId: r.CorrelationId, Deadline: r.Deadline}
+}
+
+type EscrowReleaseRequest struct {
+	Uid           int64
+	Escrow        int64
+	Account       uint32 // Account of the buyer
+	Payouts       []domain.Payout
+	CorrelationId string
+	Deadline      time.Time
+}
+
+func (r *EscrowReleaseRequest) Validate() error {
+	err := validate(
+		validateUid(r.Uid),
+		validateEscrow(r.Escrow),
+		validateAccount(r.Account),
+	)
+	if err != nil {
+		return err
+	}
+	if len(r.Payouts) == 0 {
+		return domain.ErrPayoutsRequired
+	}
+
+	sellers := make(map[uint32]bool, len(r.Payouts))
+	for _, payout := range r.Payouts {
+		err := validate(
+			validateAccount(payout.Account),
+			validateAmount(payout.Amount),
+		)
+		if err != nil {
+			return err
+		}
+		if sellers[payout.Account] {
+			return domain.ErrDuplicatePayout
+		}
+		sellers[payout.Account] = true
+	}
+	return nil
+}
+
+func (r *EscrowReleaseRequest) Header() Header {
+	return Header{Uid: r.Uid, CorrelationId: r.CorrelationId, Deadline: r.Deadline}
+}
+
+type EscrowRefundRequest struct {
+	Uid           int64
+	Escrow        int64
+	Account       uint32 // Account of the buyer
+	Amount        float32
+	CorrelationId string
+	Deadline      time.Time
+}
+
+func (r *EscrowRefundRequest) Validate() error {
+	return validate(
+		validateUid(r.Uid),
+		validateEscrow(r.Escrow),
+		validateAccount(r.Account),
+		validateAmount(r.Amount),
+	)
+}
+
+func (r *EscrowRefundRequest) Header() Header {
+	return Header{Uid: r.Uid, CorrelationId: r.CorrelationId, Deadline: r.Deadline}
+}
+
+// Get first failed rule
+func validate(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateUid(uid int64) error {
+	if uid == 0 {
+		return domain.ErrUidRequired
+	}
+	return nil
+}
+
+func validateAccount(account uint32) error {
+	if account == 0 {
+		return domain.ErrAccountRequired
+	}
+	return nil
+}
+
+func validateOriginal(original int64) error {
+	if original == 0 {
+		return domain.ErrOriginalRequired
+	}
+	return nil
+}
+
+func validatePeer(peer string) error {
+	if peer == "" {
+		return domain.ErrPeerRequired
+	}
+	return nil
+}
+
+func validateEscrow(escrow int64) error {
+	if escrow == 0 {
+		return domain.ErrEscrowRequired
+	}
+	return nil
+}
+
+func validateAmount(amount float32) error {
+	value := float64(amount)
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return domain.ErrAmountNotFinite
+	}
+	if amount <= 0 {
+		return domain.ErrAmountNotPositive
+	}
+	return nil
 }
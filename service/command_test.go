@@ -0,0 +1,237 @@
+package service
+
+import (
+	"billing/domain"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+type validator interface {
+	Validate() error
+}
+
+func testValidation(t *testing.T, tests map[string]validator, expected map[string]error) {
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, expected[name], test.Validate())
+		})
+	}
+}
+
+func TestCreditRequest_Validate(t *testing.T) {
+	tests := map[string]validator{
+		"Valid request must be accepted":     &CreditRequest{Uid: 1, Account: 1, Amount: 10},
+		"Zero uid must be rejected":          &CreditRequest{Uid: 0, Account: 1, Amount: 10},
+		"Zero account must be rejected":      &CreditRequest{Uid: 1, Account: 0, Amount: 10},
+		"Zero amount must be rejected":       &CreditRequest{Uid: 1, Account: 1, Amount: 0},
+		"Negative amount must be rejected":   &CreditRequest{Uid: 1, Account: 1, Amount: -10},
+		"NaN amount must be rejected":        &CreditRequest{Uid: 1, Account: 1, Amount: float32(math.NaN())},
+		"Infinite amount must be rejected":   &CreditRequest{Uid: 1, Account: 1, Amount: float32(math.Inf(1))},
+		"Negative infinity must be rejected": &CreditRequest{Uid: 1, Account: 1, Amount: float32(math.Inf(-1))},
+		"First failed rule must be reported": &CreditRequest{Uid: 0, Account: 0, Amount: -10},
+		"Fractional amount must be accepted": &CreditRequest{Uid: 1, Account: 1, Amount: 0.001},
+	}
+
+	testValidation(t, tests, map[string]error{
+		"Zero uid must be rejected":          domain.ErrUidRequired,
+		"Zero account must be rejected":      domain.ErrAccountRequired,
+		"Zero amount must be rejected":       domain.ErrAmountNotPositive,
+		"Negative amount must be rejected":   domain.ErrAmountNotPositive,
+		"NaN amount must be rejected":        domain.ErrAmountNotFinite,
+		"Infinite amount must be rejected":   domain.ErrAmountNotFinite,
+		"Negative infinity must be rejected": domain.ErrAmountNotFinite,
+		"First failed rule must be reported": domain.ErrUidRequired,
+	})
+}
+
+func TestDebitRequest_Validate(t *testing.T) {
+	tests := map[string]validator{
+		"Valid request must be accepted":   &DebitRequest{Uid: 1, Account: 1, Amount: 10},
+		"Zero uid must be rejected":        &DebitRequest{Uid: 0, Account: 1, Amount: 10},
+		"Zero account must be rejected":    &DebitRequest{Uid: 1, Account: 0, Amount: 10},
+		"Zero amount must be rejected":     &DebitRequest{Uid: 1, Account: 1, Amount: 0},
+		"Negative amount must be rejected": &DebitRequest{Uid: 1, Account: 1, Amount: -10},
+		"NaN amount must be rejected":      &DebitRequest{Uid: 1, Account: 1, Amount: float32(math.NaN())},
+		"Infinite amount must be rejected": &DebitRequest{Uid: 1, Account: 1, Amount: float32(math.Inf(1))},
+	}
+
+	testValidation(t, tests, map[string]error{
+		"Zero uid must be rejected":        domain.ErrUidRequired,
+		"Zero account must be rejected":    domain.ErrAccountRequired,
+		"Zero amount must be rejected":     domain.ErrAmountNotPositive,
+		"Negative amount must be rejected": domain.ErrAmountNotPositive,
+		"NaN amount must be rejected":      domain.ErrAmountNotFinite,
+		"Infinite amount must be rejected": domain.ErrAmountNotFinite,
+	})
+}
+
+func TestDepositRequest_Validate(t *testing.T) {
+	tests := map[string]validator{
+		"Valid request must be accepted":   &DepositRequest{Uid: 1, Peer: "billing-a", Account: 1, Amount: 10},
+		"Zero uid must be rejected":        &DepositRequest{Uid: 0, Peer: "billing-a", Account: 1, Amount: 10},
+		"Empty peer must be rejected":      &DepositRequest{Uid: 1, Peer: "", Account: 1, Amount: 10},
+		"Zero account must be rejected":    &DepositRequest{Uid: 1, Peer: "billing-a", Account: 0, Amount: 10},
+		"Negative amount must be rejected": &DepositRequest{Uid: 1, Peer: "billing-a", Account: 1, Amount: -10},
+	}
+
+	testValidation(t, tests, map[string]error{
+		"Zero uid must be rejected":        domain.ErrUidRequired,
+		"Empty peer must be rejected":      domain.ErrPeerRequired,
+		"Zero account must be rejected":    domain.ErrAccountRequired,
+		"Negative amount must be rejected": domain.ErrAmountNotPositive,
+	})
+}
+
+func TestTransferRequest_Validate(t *testing.T) {
+	tests := map[string]validator{
+		"Valid request must be accepted":    &TransferRequest{Uid: 1, Src: 1, Dst: 2, Amount: 10},
+		"Zero uid must be rejected":         &TransferRequest{Uid: 0, Src: 1, Dst: 2, Amount: 10},
+		"Zero source must be rejected":      &TransferRequest{Uid: 1, Src: 0, Dst: 2, Amount: 10},
+		"Zero destination must be rejected": &TransferRequest{Uid: 1, Src: 1, Dst: 0, Amount: 10},
+		"Zero amount must be rejected":      &TransferRequest{Uid: 1, Src: 1, Dst: 2, Amount: 0},
+		"Negative amount must be rejected":  &TransferRequest{Uid: 1, Src: 1, Dst: 2, Amount: -10},
+		"NaN amount must be rejected":       &TransferRequest{Uid: 1, Src: 1, Dst: 2, Amount: float32(math.NaN())},
+		"Same accounts must be rejected":    &TransferRequest{Uid: 1, Src: 1, Dst: 1, Amount: 10},
+		"Field rules must be checked first": &TransferRequest{Uid: 1, Src: 1, Dst: 1, Amount: -10},
+	}
+
+	testValidation(t, tests, map[string]error{
+		"Zero uid must be rejected":         domain.ErrUidRequired,
+		"Zero source must be rejected":      domain.ErrAccountRequired,
+		"Zero destination must be rejected": domain.ErrAccountRequired,
+		"Zero amount must be rejected":      domain.ErrAmountNotPositive,
+		"Negative amount must be rejected":  domain.ErrAmountNotPositive,
+		"NaN amount must be rejected":       domain.ErrAmountNotFinite,
+		"Same accounts must be rejected":    domain.ErrSameAccount,
+		"Field rules must be checked first": domain.ErrAmountNotPositive,
+	})
+}
+
+func TestAcquireRequest_Validate(t *testing.T) {
+	tests := map[string]validator{
+		"Valid request must be accepted":         &AcquireRequest{Uid: 1, Account: 1, Amount: 10},
+		"Hold with beneficiary must be accepted": &AcquireRequest{Uid: 1, Account: 1, Dst: 2, Amount: 10},
+		"Zero uid must be rejected":              &AcquireRequest{Uid: 0, Account: 1, Amount: 10},
+		"Zero account must be rejected":          &AcquireRequest{Uid: 1, Account: 0, Amount: 10},
+		"Zero amount must be rejected":           &AcquireRequest{Uid: 1, Account: 1, Amount: 0},
+		"Negative amount must be rejected":       &AcquireRequest{Uid: 1, Account: 1, Amount: -10},
+		"NaN amount must be rejected":            &AcquireRequest{Uid: 1, Account: 1, Amount: float32(math.NaN())},
+		"Same beneficiary must be rejected":      &AcquireRequest{Uid: 1, Account: 1, Dst: 1, Amount: 10},
+	}
+
+	testValidation(t, tests, map[string]error{
+		"Zero uid must be rejected":         domain.ErrUidRequired,
+		"Zero account must be rejected":     domain.ErrAccountRequired,
+		"Zero amount must be rejected":      domain.ErrAmountNotPositive,
+		"Negative amount must be rejected":  domain.ErrAmountNotPositive,
+		"NaN amount must be rejected":       domain.ErrAmountNotFinite,
+		"Same beneficiary must be rejected": domain.ErrSameAccount,
+	})
+}
+
+func TestCommitRequest_Validate(t *testing.T) {
+	tests := map[string]validator{
+		"Valid request must be accepted": &CommitRequest{Uid: 1, Account: 1},
+		"Zero uid must be rejected":      &CommitRequest{Uid: 0, Account: 1},
+		"Zero account must be rejected":  &CommitRequest{Uid: 1, Account: 0},
+	}
+
+	testValidation(t, tests, map[string]error{
+		"Zero uid must be rejected":     domain.ErrUidRequired,
+		"Zero account must be rejected": domain.ErrAccountRequired,
+	})
+}
+
+func TestRollbackRequest_Validate(t *testing.T) {
+	tests := map[string]validator{
+		"Valid request must be accepted": &RollbackRequest{Uid: 1, Account: 1},
+		"Zero uid must be rejected":      &RollbackRequest{Uid: 0, Account: 1},
+		"Zero account must be rejected":  &RollbackRequest{Uid: 1, Account: 0},
+	}
+
+	testValidation(t, tests, map[string]error{
+		"Zero uid must be rejected":     domain.ErrUidRequired,
+		"Zero account must be rejected": domain.ErrAccountRequired,
+	})
+}
+
+func TestReverseRequest_Validate(t *testing.T) {
+	tests := map[string]validator{
+		"Valid request must be accepted":   &ReverseRequest{Uid: 2, Original: 1, Account: 1, Amount: 10},
+		"Whole rest must be accepted":      &ReverseRequest{Uid: 2, Original: 1, Account: 1},
+		"Transfer must be accepted":        &ReverseRequest{Uid: 2, Original: 1, Account: 1, Dst: 2},
+		"Zero original must be rejected":   &ReverseRequest{Uid: 2, Original: 0, Account: 1},
+		"Zero account must be rejected":    &ReverseRequest{Uid: 2, Original: 1, Account: 0},
+		"Negative amount must be rejected": &ReverseRequest{Uid: 2, Original: 1, Account: 1, Amount: -10},
+		"NaN amount must be rejected":      &ReverseRequest{Uid: 2, Original: 1, Account: 1, Amount: float32(math.NaN())},
+		"Same recipient must be rejected":  &ReverseRequest{Uid: 2, Original: 1, Account: 1, Dst: 1},
+	}
+
+	testValidation(t, tests, map[string]error{
+		"Zero original must be rejected":   domain.ErrOriginalRequired,
+		"Zero account must be rejected":    domain.ErrAccountRequired,
+		"Negative amount must be rejected": domain.ErrAmountNotPositive,
+		"NaN amount must be rejected":      domain.ErrAmountNotFinite,
+		"Same recipient must be rejected":  domain.ErrSameAccount,
+	})
+}
+
+func TestEscrowOpenRequest_Validate(t *testing.T) {
+	tests := map[string]validator{
+		"Valid request must be accepted": &EscrowOpenRequest{Uid: 1, Escrow: 1, Account: 1, Amount: 10},
+		"Zero escrow must be rejected":   &EscrowOpenRequest{Uid: 1, Escrow: 0, Account: 1, Amount: 10},
+		"Zero amount must be rejected":   &EscrowOpenRequest{Uid: 1, Escrow: 1, Account: 1, Amount: 0},
+	}
+
+	testValidation(t, tests, map[string]error{
+		"Zero escrow must be rejected": domain.ErrEscrowRequired,
+		"Zero amount must be rejected": domain.ErrAmountNotPositive,
+	})
+}
+
+func TestEscrowReleaseRequest_Validate(t *testing.T) {
+	payout := domain.Payout{Account: 2, Amount: 10}
+
+	tests := map[string]validator{
+		"Valid request must be accepted": &EscrowReleaseRequest{
+			Uid: 1, Escrow: 1, Account: 1, Payouts: []domain.Payout{payout, {Account: 3, Amount: 5}},
+		},
+		"Zero escrow must be rejected": &EscrowReleaseRequest{
+			Uid: 1, Escrow: 0, Account: 1, Payouts: []domain.Payout{payout},
+		},
+		"Empty payouts must be rejected": &EscrowReleaseRequest{
+			Uid: 1, Escrow: 1, Account: 1,
+		},
+		"Zero seller must be rejected": &EscrowReleaseRequest{
+			Uid: 1, Escrow: 1, Account: 1, Payouts: []domain.Payout{{Account: 0, Amount: 10}},
+		},
+		"Negative payout must be rejected": &EscrowReleaseRequest{
+			Uid: 1, Escrow: 1, Account: 1, Payouts: []domain.Payout{{Account: 2, Amount: -10}},
+		},
+		"Duplicated seller must be rejected": &EscrowReleaseRequest{
+			Uid: 1, Escrow: 1, Account: 1, Payouts: []domain.Payout{payout, payout},
+		},
+	}
+
+	testValidation(t, tests, map[string]error{
+		"Zero escrow must be rejected":       domain.ErrEscrowRequired,
+		"Empty payouts must be rejected":     domain.ErrPayoutsRequired,
+		"Zero seller must be rejected":       domain.ErrAccountRequired,
+		"Negative payout must be rejected":   domain.ErrAmountNotPositive,
+		"Duplicated seller must be rejected": domain.ErrDuplicatePayout,
+	})
+}
+
+func TestEscrowRefundRequest_Validate(t *testing.T) {
+	tests := map[string]validator{
+		"Valid request must be accepted": &EscrowRefundRequest{Uid: 1, Escrow: 1, Account: 1, Amount: 10},
+		"Zero escrow must be rejected":   &EscrowRefundRequest{Uid: 1, Escrow: 0, Account: 1, Amount: 10},
+		"Zero amount must be rejected":   &EscrowRefundRequest{Uid: 1, Escrow: 1, Account: 1, Amount: 0},
+	}
+
+	testValidation(t, tests, map[string]error{
+		"Zero escrow must be rejected": domain.ErrEscrowRequired,
+		"Zero amount must be rejected": domain.ErrAmountNotPositive,
+	})
+}
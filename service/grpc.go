@@ -0,0 +1,254 @@
+package service
+
+import (
+	"billing/domain"
+	"billing/manager/banker"
+	"billing/service/pb"
+	"context"
+	"fmt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"runtime/debug"
+	"time"
+)
+
+const (
+	defaultHistoryLimit = 100
+	maxHistoryLimit     = 1000
+)
+
+type grpcServer struct {
+	pb.UnimplementedBankerServer
+	manager  banker.Manager
+	handlers map[string]endpoint
+	logger   *slog.Logger
+}
+
+// Create gRPC server, that shares endpoints with the broker.
+func NewGrpcServer(
+	manager banker.Manager,
+	logger *slog.Logger,
+) *grpc.Server {
+	s := grpc.NewServer(
+		grpc.UnaryInterceptor(recoverInterceptor(logger)),
+	)
+	pb.RegisterBankerServer(s, &grpcServer{
+		manager:  manager,
+		handlers: endpoints(manager),
+		logger:   logger,
+	})
+	return s
+}
+
+func (s *grpcServer) Credit(
+	ctx context.Context,
+	r *pb.CreditRequest,
+) (*pb.OperationResponse, error) {
+	return s.command(ctx, "credit", &CreditRequest{
+		Uid:           r.Uid,
+		Account:       r.Account,
+		Amount:        r.Amount,
+		CorrelationId: r.CorrelationId,
+	})
+}
+
+func (s *grpcServer) Debit(
+	ctx context.Context,
+	r *pb.DebitRequest,
+) (*pb.OperationResponse, error) {
+	return s.command(ctx, "debit", &DebitRequest{
+		Uid:           r.Uid,
+		Account:       r.Account,
+		Amount:        r.Amount,
+		CorrelationId: r.CorrelationId,
+	})
+}
+
+func (s *grpcServer) Transfer(
+	ctx context.Context,
+	r *pb.TransferRequest,
+) (*pb.OperationResponse, error) {
+	return s.command(ctx, "transfer", &TransferRequest{
+		Uid:           r.Uid,
+		Src:           r.Src,
+		Dst:           r.Dst,
+		Amount:        r.Amount,
+		CorrelationId: r.CorrelationId,
+	})
+}
+
+func (s *grpcServer) Acquire(
+	ctx context.Context,
+	r *pb.AcquireRequest,
+) (*pb.OperationResponse, error) {
+	return s.command(ctx, "acquire", &AcquireRequest{
+		Uid:           r.Uid,
+		Account:       r.Account,
+		Dst:           r.Dst,
+		Amount:        r.Amount,
+		CorrelationId: r.CorrelationId,
+	})
+}
+
+func (s *grpcServer) Commit(
+	ctx context.Context,
+	r *pb.CommitRequest,
+) (*pb.OperationResponse, error) {
+	return s.command(ctx, "commit", &CommitRequest{
+		Uid:           r.Uid,
+		Account:       r.Account,
+		CorrelationId: r.CorrelationId,
+	})
+}
+
+func (s *grpcServer) Rollback(
+	ctx context.Context,
+	r *pb.RollbackRequest,
+) (*pb.OperationResponse, error) {
+	return s.command(ctx, "rollback", &RollbackRequest{
+		Uid:           r.Uid,
+		Account:       r.Account,
+		CorrelationId: r.CorrelationId,
+	})
+}
+
+func (s *grpcServer) Balance(
+	ctx context.Context,
+	r *pb.BalanceRequest,
+) (*pb.BalanceResponse, error) {
+	start := time.Now()
+	var amount float32
+	err := validateAccount(r.Account)
+	if err == nil {
+		amount, err = s.manager.Balance(ctx, r.Account)
+	}
+
+	response := respond(err, Header{})
+	logResult(ctx, s.requestLogger(ctx, "accounts", []uint32{r.Account}), err, response, start)
+	if err != nil {
+		return nil, grpcError(response)
+	}
+
+	return &pb.BalanceResponse{Amount: amount}, nil
+}
+
+func (s *grpcServer) History(
+	ctx context.Context,
+	r *pb.HistoryRequest,
+) (*pb.HistoryResponse, error) {
+	start := time.Now()
+	logger := s.requestLogger(ctx, "accounts", []uint32{r.Account})
+
+	err := validateAccount(r.Account)
+	if err != nil {
+		response := respond(err, Header{})
+		logResult(ctx, logger, err, response, start)
+		return nil, grpcError(response)
+	}
+
+	limit := int(r.Limit)
+	if limit == 0 {
+		limit = defaultHistoryLimit
+	}
+	if limit > maxHistoryLimit {
+		limit = maxHistoryLimit
+	}
+
+	records, err := s.manager.History(ctx, r.Account, int(r.Offset), limit)
+	response := respond(err, Header{})
+	logResult(ctx, logger, err, response, start)
+	if err != nil {
+		return nil, grpcError(response)
+	}
+
+	result := &pb.HistoryResponse{
+		Records: make([]*pb.HistoryRecord, 0, len(records)),
+	}
+	for _, record := range records {
+		result.Records = append(result.Records, &pb.HistoryRecord{
+			Id:         record.Id,
+			Uid:        record.Uid,
+			Account:    record.Account,
+			Amount:     record.Amount,
+			Op:         pb.Operation(record.Op),
+			Registered: record.Registered.Unix(),
+		})
+	}
+
+	return result, nil
+}
+
+func (s *grpcServer) command(
+	ctx context.Context,
+	key string,
+	req Request,
+) (*pb.OperationResponse, error) {
+	response, _ := execute(ctx, s.handlers[key], req, s.requestLogger(ctx))
+	if response.Status != domain.StatusOk {
+		return nil, grpcError(response)
+	}
+
+	return &pb.OperationResponse{}, nil
+}
+
+// Map failed response to the gRPC status
+func grpcError(response Response) error {
+	code := codes.Internal
+	switch response.Status {
+	case domain.StatusInvalidRequest:
+		code = codes.InvalidArgument
+	case domain.StatusNotFound:
+		code = codes.NotFound
+	case domain.StatusNoMoney:
+		code = codes.FailedPrecondition
+	case domain.StatusDeprecated:
+		code = codes.AlreadyExists
+	case domain.StatusTimeout:
+		code = codes.DeadlineExceeded
+	default:
+		if response.Error != nil && response.Error.Retryable {
+			code = codes.Unavailable
+		}
+	}
+
+	if response.Error == nil {
+		return status.Error(code, domain.ErrorCodeUnknown)
+	}
+	return status.Error(code, response.Error.Code+": "+response.Error.Message)
+}
+
+// Get logger with the called method and additional fields
+func (s *grpcServer) requestLogger(ctx context.Context, attrs ...any) *slog.Logger {
+	method, _ := grpc.Method(ctx)
+	return s.logger.With("subject", method).With(attrs...)
+}
+
+func recoverInterceptor(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (resp interface{}, err error) {
+		start := time.Now()
+		defer func() {
+			if e := recover(); e != nil {
+				failure := fmt.Errorf("panic: %v", e)
+				response := respond(failure, Header{})
+				logResult(
+					ctx,
+					logger.With("subject", info.FullMethod),
+					failure,
+					response,
+					start,
+					"stack", string(debug.Stack()),
+				)
+				err = grpcError(response)
+			}
+		}()
+
+		return handler(ctx, req)
+	}
+}
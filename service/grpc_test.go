@@ -0,0 +1,247 @@
+package service
+
+import (
+	"billing/domain"
+	"billing/service/pb"
+	"context"
+	"github.com/adverax/echo/database/sql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+)
+
+func setUpGrpc(t *testing.T, manager *managerMock) (pb.BankerClient, func()) {
+	lis := bufconn.Listen(1 << 20)
+	s := NewGrpcServer(manager, slog.New(slog.DiscardHandler))
+	go func() {
+		_ = s.Serve(lis)
+	}()
+
+	conn, err := grpc.DialContext(
+		context.Background(),
+		"bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			return lis.Dial()
+		}),
+		grpc.WithInsecure(),
+	)
+	require.NoError(t, err)
+
+	return pb.NewBankerClient(conn), func() {
+		_ = conn.Close()
+		s.Stop()
+	}
+}
+
+func TestGrpcServer_Commands(t *testing.T) {
+	type Src struct {
+		call    func(ctx context.Context, c pb.BankerClient) error
+		manager managerMock
+	}
+
+	type Dst struct {
+		code  codes.Code
+		calls []string
+	}
+
+	type Test struct {
+		src Src
+		dst Dst
+	}
+
+	tests := map[string]Test{
+		"Credit must be executed": {
+			src: Src{
+				call: func(ctx context.Context, c pb.BankerClient) error {
+					_, err := c.Credit(ctx, &pb.CreditRequest{Uid: 1, Account: 2, Amount: 3})
+					return err
+				},
+			},
+			dst: Dst{code: codes.OK, calls: []string{"credit 1 2 3"}},
+		},
+		"Debit must be executed": {
+			src: Src{
+				call: func(ctx context.Context, c pb.BankerClient) error {
+					_, err := c.Debit(ctx, &pb.DebitRequest{Uid: 1, Account: 2, Amount: 3})
+					return err
+				},
+			},
+			dst: Dst{code: codes.OK, calls: []string{"debit 1 2 3"}},
+		},
+		"Transfer must be executed": {
+			src: Src{
+				call: func(ctx context.Context, c pb.BankerClient) error {
+					_, err := c.Transfer(ctx, &pb.TransferRequest{Uid: 1, Src: 2, Dst: 3, Amount: 4})
+					return err
+				},
+			},
+			dst: Dst{code: codes.OK, calls: []string{"transfer 1 2 3 4"}},
+		},
+		"Acquire must be executed": {
+			src: Src{
+				call: func(ctx context.Context, c pb.BankerClient) error {
+					_, err := c.Acquire(ctx, &pb.AcquireRequest{Uid: 1, Account: 2, Amount: 3})
+					return err
+				},
+			},
+			dst: Dst{code: codes.OK, calls: []string{"acquire 1 2 0 3"}},
+		},
+		"Acquire with beneficiary must be executed": {
+			src: Src{
+				call: func(ctx context.Context, c pb.BankerClient) error {
+					_, err := c.Acquire(ctx, &pb.AcquireRequest{Uid: 1, Account: 2, Dst: 4, Amount: 3})
+					return err
+				},
+			},
+			dst: Dst{code: codes.OK, calls: []string{"acquire 1 2 4 3"}},
+		},
+		"Commit must be executed": {
+			src: Src{
+				call: func(ctx context.Context, c pb.BankerClient) error {
+					_, err := c.Commit(ctx, &pb.CommitRequest{Uid: 1, Account: 2})
+					return err
+				},
+			},
+			dst: Dst{code: codes.OK, calls: []string{"commit 1 2"}},
+		},
+		"Rollback must be executed": {
+			src: Src{
+				call: func(ctx context.Context, c pb.BankerClient) error {
+					_, err := c.Rollback(ctx, &pb.RollbackRequest{Uid: 1, Account: 2})
+					return err
+				},
+			},
+			dst: Dst{code: codes.OK, calls: []string{"rollback 1 2"}},
+		},
+		"Invalid request must be rejected": {
+			src: Src{
+				call: func(ctx context.Context, c pb.BankerClient) error {
+					_, err := c.Credit(ctx, &pb.CreditRequest{Uid: 1, Account: 2, Amount: -3})
+					return err
+				},
+			},
+			dst: Dst{code: codes.InvalidArgument},
+		},
+		"Insufficient funds must be reported": {
+			src: Src{
+				call: func(ctx context.Context, c pb.BankerClient) error {
+					_, err := c.Credit(ctx, &pb.CreditRequest{Uid: 1, Account: 2, Amount: 3})
+					return err
+				},
+				manager: managerMock{err: domain.ErrNoMoney},
+			},
+			dst: Dst{code: codes.FailedPrecondition, calls: []string{"credit 1 2 3"}},
+		},
+		"Deprecated operation must be reported": {
+			src: Src{
+				call: func(ctx context.Context, c pb.BankerClient) error {
+					_, err := c.Debit(ctx, &pb.DebitRequest{Uid: 1, Account: 2, Amount: 3})
+					return err
+				},
+				manager: managerMock{err: domain.ErrOperationIsDeprecated},
+			},
+			dst: Dst{code: codes.AlreadyExists, calls: []string{"debit 1 2 3"}},
+		},
+		"Missing hold must be reported": {
+			src: Src{
+				call: func(ctx context.Context, c pb.BankerClient) error {
+					_, err := c.Commit(ctx, &pb.CommitRequest{Uid: 1, Account: 2})
+					return err
+				},
+				manager: managerMock{err: sql.ErrNoRows},
+			},
+			dst: Dst{code: codes.NotFound, calls: []string{"commit 1 2"}},
+		},
+		"Panic must be reported": {
+			src: Src{
+				call: func(ctx context.Context, c pb.BankerClient) error {
+					_, err := c.Balance(ctx, &pb.BalanceRequest{Account: 2})
+					return err
+				},
+				manager: managerMock{panic: "boom"},
+			},
+			dst: Dst{code: codes.Internal, calls: []string{"balance 2"}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			manager := test.src.manager
+			client, tearDown := setUpGrpc(t, &manager)
+			defer tearDown()
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			err := test.src.call(ctx, client)
+			assert.Equal(t, test.dst.code, status.Code(err))
+			assert.Equal(t, test.dst.calls, manager.calls)
+		})
+	}
+}
+
+func TestGrpcServer_Queries(t *testing.T) {
+	registered := time.Unix(1500000000, 0)
+	manager := &managerMock{
+		balance: 42,
+		history: []*domain.HistoryRecord{
+			{
+				Id:         2,
+				Uid:        20,
+				Account:    1,
+				Amount:     5,
+				Op:         domain.OperationAcquire,
+				Registered: registered,
+			},
+			{
+				Id:         1,
+				Uid:        10,
+				Account:    1,
+				Amount:     7,
+				Op:         domain.OperationDebit,
+				Registered: registered,
+			},
+		},
+	}
+
+	client, tearDown := setUpGrpc(t, manager)
+	defer tearDown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	balance, err := client.Balance(ctx, &pb.BalanceRequest{Account: 1})
+	require.NoError(t, err)
+	assert.Equal(t, float32(42), balance.Amount)
+
+	history, err := client.History(ctx, &pb.HistoryRequest{Account: 1, Offset: 5})
+	require.NoError(t, err)
+	require.Len(t, history.Records, 2)
+	assert.Equal(t, int64(20), history.Records[0].Uid)
+	assert.Equal(t, pb.Operation_OPERATION_ACQUIRE, history.Records[0].Op)
+	assert.Equal(t, registered.Unix(), history.Records[0].Registered)
+	assert.Equal(t, pb.Operation_OPERATION_DEBIT, history.Records[1].Op)
+
+	_, err = client.History(ctx, &pb.HistoryRequest{Account: 0})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	assert.Equal(t, []string{
+		"balance 1",
+		"history 1 5 100",
+	}, manager.calls)
+}
+
+func TestGrpcOperation(t *testing.T) {
+	for op := domain.OperationCredit; op <= domain.OperationReverseDst; op++ {
+		_, ok := pb.Operation_name[int32(op)]
+		assert.True(t, ok, "operation %d must be declared in billing.proto", op)
+	}
+	assert.Equal(t, "OPERATION_REVERSE_DST", pb.Operation(domain.OperationReverseDst).String())
+}
@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/adverax/echo/database/sql"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Timeout of the checks of the single probe
+const healthCheckTimeout = 2 * time.Second
+
+var errNotChecked = errors.New("database is not checked yet")
+
+// Check of the dependency of the service. Returns error, if the dependency is not available.
+type Check func(ctx context.Context) error
+
+// State of the service for the probes of the orchestrator
+type Health struct {
+	mu       sync.RWMutex
+	checks   map[string]Check
+	stopping int32
+}
+
+// Register check of the dependency
+func (h *Health) Register(name string, check Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks[name] = check
+}
+
+// Mark service as stopping, so it does not receive new requests
+func (h *Health) Stop() {
+	atomic.StoreInt32(&h.stopping, 1)
+}
+
+// Get results of the checks by name ("ok" or error) and readiness of the service
+func (h *Health) Check(ctx context.Context) (results map[string]string, ready bool) {
+	h.mu.RLock()
+	names := make([]string, 0, len(h.checks))
+	checks := make(map[string]Check, len(h.checks))
+	for name, check := range h.checks {
+		names = append(names, name)
+		checks[name] = check
+	}
+	h.mu.RUnlock()
+	sort.Strings(names)
+
+	ready = atomic.LoadInt32(&h.stopping) == 0
+	results = make(map[string]string, len(names))
+	for _, name := range names {
+		err := checks[name](ctx)
+		if err != nil {
+			results[name] = err.Error()
+			ready = false
+			continue
+		}
+		results[name] = "ok"
+	}
+
+	return results, ready
+}
+
+// Get handler of the probes:
+// /healthz - liveness, that succeeds while the process serves requests;
+// /readyz - readiness, that fails, if any check fails or the service is stopping.
+// Both probes report results of the checks.
+func (h *Health) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		h.serve(w, r, false)
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		h.serve(w, r, true)
+	})
+	return mux
+}
+
+type healthResponse struct {
+	Status   string            `json:"status"`
+	Stopping bool              `json:"stopping"`
+	Checks   map[string]string `json:"checks"`
+}
+
+func (h *Health) serve(
+	w http.ResponseWriter,
+	r *http.Request,
+	readiness bool,
+) {
+	ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+	defer cancel()
+
+	results, ready := h.Check(ctx)
+	response := healthResponse{
+		Status:   "ok",
+		Stopping: atomic.LoadInt32(&h.stopping) != 0,
+		Checks:   results,
+	}
+	if !ready {
+		response.Status = "unavailable"
+	}
+
+	status := http.StatusOK
+	if readiness && !ready {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func NewHealth() *Health {
+	return &Health{
+		checks: make(map[string]Check),
+	}
+}
+
+// Periodic check of the database connection.
+// Pool of the connections replaces broken connections on the next query,
+// so connection is restored automatically, when the database becomes available.
+type Heartbeat struct {
+	ping     Check
+	interval time.Duration
+	logger   *slog.Logger
+	mu       sync.RWMutex
+	err      error
+}
+
+// Ping database every interval (must be positive) until the context is done.
+// Loss and recovery of the connection are logged.
+func (h *Heartbeat) Run(ctx context.Context) {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	for {
+		h.beat(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Get result of the last ping.
+// Without interval database is pinged on demand.
+func (h *Heartbeat) Check(ctx context.Context) error {
+	if h.interval <= 0 {
+		return h.ping(ctx)
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.err
+}
+
+func (h *Heartbeat) beat(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, h.interval)
+	defer cancel()
+
+	err := h.ping(ctx)
+
+	h.mu.Lock()
+	prev := h.err
+	h.err = err
+	h.mu.Unlock()
+
+	switch {
+	case err != nil && prev == nil:
+		h.logger.Error("Database connection is lost", "error", err)
+	case err != nil && prev == errNotChecked:
+		h.logger.Error("Database is not available", "error", err)
+	case err == nil && prev != nil && prev != errNotChecked:
+		h.logger.Info("Database connection is restored")
+	}
+}
+
+// Create heartbeat of the database with interval (zero for pings on demand)
+func NewHeartbeat(
+	ping Check,
+	interval time.Duration,
+	logger *slog.Logger,
+) *Heartbeat {
+	return &Heartbeat{
+		ping:     ping,
+		interval: interval,
+		logger:   logger,
+		err:      errNotChecked,
+	}
+}
+
+// Get check, that database executes queries
+func PingCheck(db sql.Scope) Check {
+	return func(ctx context.Context) error {
+		var one int
+		return db.QueryRowContext(ctx, "SELECT 1").Scan(&one)
+	}
+}
+
+// Get check, that all tables of the schema are created by the migrations
+func SchemaCheck(
+	db sql.Scope,
+	tables ...string,
+) Check {
+	return func(ctx context.Context) error {
+		const query = "SELECT table_name FROM information_schema.tables WHERE table_schema = DATABASE()"
+		rows, err := db.QueryContext(ctx, query)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		exists := make(map[string]bool)
+		for rows.Next() {
+			var table string
+			err := rows.Scan(&table)
+			if err != nil {
+				return err
+			}
+			exists[table] = true
+		}
+		err = rows.Err()
+		if err != nil {
+			return err
+		}
+
+		var missing []string
+		for _, table := range tables {
+			if !exists[table] {
+				missing = append(missing, table)
+			}
+		}
+		if len(missing) != 0 {
+			return fmt.Errorf("missing tables: %s", strings.Join(missing, ", "))
+		}
+
+		return nil
+	}
+}
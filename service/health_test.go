@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHealth_Handler(t *testing.T) {
+	type Src struct {
+		path     string
+		err      error
+		stopping bool
+	}
+
+	type Dst struct {
+		code     int
+		status   string
+		database string
+	}
+
+	type Test struct {
+		src Src
+		dst Dst
+	}
+
+	tests := map[string]Test{
+		"Ready": {
+			src: Src{
+				path: "/readyz",
+			},
+			dst: Dst{
+				code:     http.StatusOK,
+				status:   "ok",
+				database: "ok",
+			},
+		},
+		"Not ready": {
+			src: Src{
+				path: "/readyz",
+				err:  errors.New("connection refused"),
+			},
+			dst: Dst{
+				code:     http.StatusServiceUnavailable,
+				status:   "unavailable",
+				database: "connection refused",
+			},
+		},
+		"Stopping": {
+			src: Src{
+				path:     "/readyz",
+				stopping: true,
+			},
+			dst: Dst{
+				code:     http.StatusServiceUnavailable,
+				status:   "unavailable",
+				database: "ok",
+			},
+		},
+		"Alive without database": {
+			src: Src{
+				path: "/healthz",
+				err:  errors.New("connection refused"),
+			},
+			dst: Dst{
+				code:     http.StatusOK,
+				status:   "unavailable",
+				database: "connection refused",
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			health := NewHealth()
+			health.Register("database", func(ctx context.Context) error {
+				return test.src.err
+			})
+			if test.src.stopping {
+				health.Stop()
+			}
+
+			w := httptest.NewRecorder()
+			health.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.src.path, nil))
+
+			assert.Equal(t, test.dst.code, w.Code)
+			var response healthResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, test.dst.status, response.Status)
+			assert.Equal(t, test.src.stopping, response.Stopping)
+			assert.Equal(t, map[string]string{"database": test.dst.database}, response.Checks)
+		})
+	}
+}
+
+func TestHeartbeat(t *testing.T) {
+	pings := make(chan error)
+	heartbeat := NewHeartbeat(
+		func(ctx context.Context) error {
+			select {
+			case err := <-pings:
+				return err
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+		time.Millisecond,
+		slog.New(slog.DiscardHandler),
+	)
+	assert.Equal(t, errNotChecked, heartbeat.Check(context.Background()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		heartbeat.Run(ctx)
+		close(done)
+	}()
+
+	// Result of the ping is stored before the next ping
+	lost := errors.New("connection is lost")
+	pings <- lost
+	pings <- lost
+	assert.Equal(t, lost, heartbeat.Check(context.Background()))
+
+	pings <- nil
+	pings <- nil
+	assert.NoError(t, heartbeat.Check(context.Background()))
+
+	cancel()
+	<-done
+}
@@ -0,0 +1,306 @@
+package service
+
+import (
+	"billing/domain"
+	"billing/manager/banker"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const maxRequestSize = 1 << 20
+
+type BalanceResponse struct {
+	Response
+	Balance float32
+}
+
+type EscrowResponse struct {
+	Response
+	Escrow  *domain.Escrow         `json:",omitempty"`
+	Records []*domain.EscrowRecord `json:",omitempty"`
+}
+
+type httpHandler struct {
+	ctx      context.Context
+	manager  banker.Manager
+	escrow   banker.Escrow
+	handlers map[string]endpoint
+	logger   *slog.Logger
+}
+
+// Create REST API handler, that shares endpoints with the broker.
+// Routes:
+//
+//	POST /accounts/{id}/credit
+//	POST /accounts/{id}/debit
+//	POST /accounts/{id}/holds
+//	POST /accounts/{id}/reversals
+//	GET  /accounts/{id}/balance
+//	POST /transfers
+//	POST /holds/{uid}/commit
+//	POST /holds/{uid}/rollback
+//
+// Routes of the escrow:
+//
+//	POST /escrows/{id}/open
+//	POST /escrows/{id}/release
+//	POST /escrows/{id}/refund
+//	GET  /escrows/{id}?account={account}
+func NewHttpHandler(
+	ctx context.Context,
+	manager banker.Manager,
+	escrow banker.Escrow,
+	logger *slog.Logger,
+) http.Handler {
+	return &httpHandler{
+		ctx:      ctx,
+		manager:  manager,
+		escrow:   escrow,
+		handlers: allEndpoints(manager, escrow),
+		logger:   logger,
+	}
+}
+
+func (h *httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	switch {
+	case len(path) == 3 && path[0] == "accounts":
+		account, err := strconv.ParseUint(path[1], 10, 32)
+		if err != nil {
+			break
+		}
+		id := uint32(account)
+
+		switch path[2] {
+		case "credit":
+			h.command(w, r, "credit", func(req Request) {
+				req.(*CreditRequest).Account = id
+			})
+			return
+		case "debit":
+			h.command(w, r, "debit", func(req Request) {
+				req.(*DebitRequest).Account = id
+			})
+			return
+		case "deposits":
+			h.command(w, r, "deposit", func(req Request) {
+				req.(*DepositRequest).Account = id
+			})
+			return
+		case "holds":
+			h.command(w, r, "acquire", func(req Request) {
+				req.(*AcquireRequest).Account = id
+			})
+			return
+		case "reversals":
+			h.command(w, r, "reverse", func(req Request) {
+				req.(*ReverseRequest).Account = id
+			})
+			return
+		case "balance":
+			h.balance(w, r, id)
+			return
+		}
+
+	case len(path) == 1 && path[0] == "transfers":
+		h.command(w, r, "transfer", func(req Request) {})
+		return
+
+	case len(path) == 3 && path[0] == "holds":
+		uid, err := strconv.ParseInt(path[1], 10, 64)
+		if err != nil {
+			break
+		}
+
+		switch path[2] {
+		case "commit":
+			h.command(w, r, "commit", func(req Request) {
+				req.(*CommitRequest).Uid = uid
+			})
+			return
+		case "rollback":
+			h.command(w, r, "rollback", func(req Request) {
+				req.(*RollbackRequest).Uid = uid
+			})
+			return
+		}
+
+	case (len(path) == 2 || len(path) == 3) && path[0] == "escrows":
+		id, err := strconv.ParseInt(path[1], 10, 64)
+		if err != nil {
+			break
+		}
+
+		if len(path) == 2 {
+			h.escrowInfo(w, r, id)
+			return
+		}
+
+		switch path[2] {
+		case "open":
+			h.command(w, r, "escrow.open", func(req Request) {
+				req.(*EscrowOpenRequest).Escrow = id
+			})
+			return
+		case "release":
+			h.command(w, r, "escrow.release", func(req Request) {
+				req.(*EscrowReleaseRequest).Escrow = id
+			})
+			return
+		case "refund":
+			h.command(w, r, "escrow.refund", func(req Request) {
+				req.(*EscrowRefundRequest).Escrow = id
+			})
+			return
+		}
+	}
+
+	http.NotFound(w, r)
+}
+
+// Execute command with body of the request and parameters of the route.
+func (h *httpHandler) command(
+	w http.ResponseWriter,
+	r *http.Request,
+	key string,
+	bind func(req Request),
+) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	logger := h.logger.With("subject", r.Method+" "+r.URL.Path)
+	handler := h.handlers[key]
+	req := handler.request()
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize)).Decode(req)
+	if err != nil {
+		logger.Warn("Request is malformed", "error", err)
+		writeResponse(w, respond(domain.ErrMalformedRequest, Header{}))
+		return
+	}
+
+	bind(req)
+	response, _ := execute(h.ctx, handler, req, logger)
+	writeResponse(w, response)
+}
+
+func (h *httpHandler) balance(
+	w http.ResponseWriter,
+	r *http.Request,
+	account uint32,
+) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	start := time.Now()
+	var amount float32
+	err := validateAccount(account)
+	if err == nil {
+		amount, err = h.manager.Balance(h.ctx, account)
+	}
+
+	header := Header{CorrelationId: r.Header.Get("X-Correlation-Id")}
+	response := respond(err, header)
+	logger := h.logger.With(
+		"subject", r.Method+" "+r.URL.Path,
+		"accounts", []uint32{account},
+		"correlation_id", header.CorrelationId,
+	)
+	logResult(h.ctx, logger, err, response, start)
+
+	writeResponse(w, BalanceResponse{
+		Response: response,
+		Balance:  amount,
+	})
+}
+
+// Get escrow with its movements (audit trail)
+func (h *httpHandler) escrowInfo(
+	w http.ResponseWriter,
+	r *http.Request,
+	id int64,
+) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	start := time.Now()
+	var escrow *domain.Escrow
+	var records []*domain.EscrowRecord
+	account, _ := strconv.ParseUint(r.URL.Query().Get("account"), 10, 32)
+	err := validate(
+		validateEscrow(id),
+		validateAccount(uint32(account)),
+	)
+	if err == nil {
+		escrow, records, err = h.escrow.Get(h.ctx, id, uint32(account))
+	}
+
+	header := Header{CorrelationId: r.Header.Get("X-Correlation-Id")}
+	response := respond(err, header)
+	logger := h.logger.With(
+		"subject", r.Method+" "+r.URL.Path,
+		"escrow", id,
+		"accounts", []uint32{uint32(account)},
+		"correlation_id", header.CorrelationId,
+	)
+	logResult(h.ctx, logger, err, response, start)
+
+	writeResponse(w, EscrowResponse{
+		Response: response,
+		Escrow:   escrow,
+		Records:  records,
+	})
+}
+
+func methodNotAllowed(w http.ResponseWriter, allowed string) {
+	w.Header().Set("Allow", allowed)
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+}
+
+func writeResponse(w http.ResponseWriter, response interface{}) {
+	code := http.StatusOK
+	switch r := response.(type) {
+	case Response:
+		code = httpStatus(r)
+	case BalanceResponse:
+		code = httpStatus(r.Response)
+	case EscrowResponse:
+		code = httpStatus(r.Response)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// Map status of the operation to the HTTP status code
+func httpStatus(response Response) int {
+	switch response.Status {
+	case domain.StatusOk:
+		return http.StatusOK
+	case domain.StatusInvalidRequest:
+		return http.StatusBadRequest
+	case domain.StatusNotFound:
+		return http.StatusNotFound
+	case domain.StatusNoMoney, domain.StatusDeprecated:
+		return http.StatusConflict
+	case domain.StatusTimeout:
+		return http.StatusGatewayTimeout
+	}
+
+	if response.Error != nil && response.Error.Retryable {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
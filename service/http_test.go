@@ -0,0 +1,358 @@
+package service
+
+import (
+	"billing/domain"
+	"context"
+	"github.com/adverax/echo/database/sql"
+	"github.com/stretchr/testify/assert"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHttpHandler(t *testing.T) {
+	type Src struct {
+		method  string
+		path    string
+		body    string
+		manager managerMock
+	}
+
+	type Dst struct {
+		code  int
+		body  string
+		calls []string
+	}
+
+	type Test struct {
+		src Src
+		dst Dst
+	}
+
+	tests := map[string]Test{
+		"Credit must be executed": {
+			src: Src{
+				method: http.MethodPost,
+				path:   "/accounts/1/credit",
+				body:   `{"uid":10,"amount":5}`,
+			},
+			dst: Dst{
+				code:  http.StatusOK,
+				body:  `{"Status":0}`,
+				calls: []string{"credit 10 1 5"},
+			},
+		},
+		"Debit must be executed": {
+			src: Src{
+				method: http.MethodPost,
+				path:   "/accounts/2/debit",
+				body:   `{"uid":11,"amount":7.5}`,
+			},
+			dst: Dst{
+				code:  http.StatusOK,
+				body:  `{"Status":0}`,
+				calls: []string{"debit 11 2 7.5"},
+			},
+		},
+		"Hold must be acquired": {
+			src: Src{
+				method: http.MethodPost,
+				path:   "/accounts/3/holds",
+				body:   `{"uid":12,"amount":1}`,
+			},
+			dst: Dst{
+				code:  http.StatusOK,
+				body:  `{"Status":0}`,
+				calls: []string{"acquire 12 3 0 1"},
+			},
+		},
+		"Hold with beneficiary must be acquired": {
+			src: Src{
+				method: http.MethodPost,
+				path:   "/accounts/3/holds",
+				body:   `{"uid":12,"dst":4,"amount":1}`,
+			},
+			dst: Dst{
+				code:  http.StatusOK,
+				body:  `{"Status":0}`,
+				calls: []string{"acquire 12 3 4 1"},
+			},
+		},
+		"Transfer must be executed": {
+			src: Src{
+				method: http.MethodPost,
+				path:   "/transfers",
+				body:   `{"uid":13,"src":1,"dst":2,"amount":3}`,
+			},
+			dst: Dst{
+				code:  http.StatusOK,
+				body:  `{"Status":0}`,
+				calls: []string{"transfer 13 1 2 3"},
+			},
+		},
+		"Hold must be committed": {
+			src: Src{
+				method: http.MethodPost,
+				path:   "/holds/14/commit",
+				body:   `{"account":4}`,
+			},
+			dst: Dst{
+				code:  http.StatusOK,
+				body:  `{"Status":0}`,
+				calls: []string{"commit 14 4"},
+			},
+		},
+		"Hold must be rolled back": {
+			src: Src{
+				method: http.MethodPost,
+				path:   "/holds/15/rollback",
+				body:   `{"account":5}`,
+			},
+			dst: Dst{
+				code:  http.StatusOK,
+				body:  `{"Status":0}`,
+				calls: []string{"rollback 15 5"},
+			},
+		},
+		"Balance must be returned": {
+			src: Src{
+				method:  http.MethodGet,
+				path:    "/accounts/6/balance",
+				manager: managerMock{balance: 99.5},
+			},
+			dst: Dst{
+				code:  http.StatusOK,
+				body:  `{"Status":0,"Balance":99.5}`,
+				calls: []string{"balance 6"},
+			},
+		},
+		"Missing account must be reported": {
+			src: Src{
+				method:  http.MethodGet,
+				path:    "/accounts/7/balance",
+				manager: managerMock{err: sql.ErrNoRows},
+			},
+			dst: Dst{
+				code:  http.StatusNotFound,
+				body:  `{"Status":4,"Error":{"Code":"not_found","Message":"account or hold is not found","Retryable":false,"Uid":0},"Balance":0}`,
+				calls: []string{"balance 7"},
+			},
+		},
+		"Path parameters must override body": {
+			src: Src{
+				method: http.MethodPost,
+				path:   "/accounts/8/credit",
+				body:   `{"uid":16,"account":100,"amount":1}`,
+			},
+			dst: Dst{
+				code:  http.StatusOK,
+				body:  `{"Status":0}`,
+				calls: []string{"credit 16 8 1"},
+			},
+		},
+		"Invalid request must be rejected": {
+			src: Src{
+				method: http.MethodPost,
+				path:   "/accounts/1/credit",
+				body:   `{"uid":17,"amount":-1}`,
+			},
+			dst: Dst{
+				code: http.StatusBadRequest,
+				body: `{"Status":5,"Error":{"Code":"amount_not_positive","Message":"amount must be greater than zero","Retryable":false,"Uid":17}}`,
+			},
+		},
+		"Malformed request must be rejected": {
+			src: Src{
+				method: http.MethodPost,
+				path:   "/transfers",
+				body:   `{"uid":`,
+			},
+			dst: Dst{
+				code: http.StatusBadRequest,
+				body: `{"Status":5,"Error":{"Code":"malformed_request","Message":"request cannot be decoded","Retryable":false,"Uid":0}}`,
+			},
+		},
+		"Insufficient funds must be reported": {
+			src: Src{
+				method:  http.MethodPost,
+				path:    "/accounts/1/credit",
+				body:    `{"uid":18,"amount":1,"correlationId":"abc"}`,
+				manager: managerMock{err: domain.ErrNoMoney},
+			},
+			dst: Dst{
+				code:  http.StatusConflict,
+				body:  `{"Status":3,"Error":{"Code":"no_money","Message":"insufficient funds","Retryable":false,"Uid":18,"CorrelationId":"abc"}}`,
+				calls: []string{"credit 18 1 1"},
+			},
+		},
+		"Panic must be reported": {
+			src: Src{
+				method:  http.MethodPost,
+				path:    "/accounts/1/debit",
+				body:    `{"uid":19,"amount":1}`,
+				manager: managerMock{panic: "boom"},
+			},
+			dst: Dst{
+				code:  http.StatusInternalServerError,
+				body:  `{"Status":1,"Error":{"Code":"unknown","Message":"internal error","Retryable":false,"Uid":19}}`,
+				calls: []string{"debit 19 1 1"},
+			},
+		},
+		"Transfer of the peer must be deposited": {
+			src: Src{
+				method: http.MethodPost,
+				path:   "/accounts/2001/deposits",
+				body:   `{"uid":27,"peer":"billing-a","amount":30}`,
+			},
+			dst: Dst{
+				code:  http.StatusOK,
+				body:  `{"Status":0}`,
+				calls: []string{"deposit billing-a 27 2001 30"},
+			},
+		},
+		"Deposit without peer must be rejected": {
+			src: Src{
+				method: http.MethodPost,
+				path:   "/accounts/2001/deposits",
+				body:   `{"uid":28,"amount":30}`,
+			},
+			dst: Dst{
+				code: http.StatusBadRequest,
+				body: `{"Status":5,"Error":{"Code":"peer_required","Message":"name of the sending instance is required","Retryable":false,"Uid":28}}`,
+			},
+		},
+		"Operation must be reversed": {
+			src: Src{
+				method: http.MethodPost,
+				path:   "/accounts/2/reversals",
+				body:   `{"uid":25,"original":11,"amount":2.5}`,
+			},
+			dst: Dst{
+				code:  http.StatusOK,
+				body:  `{"Status":0}`,
+				calls: []string{"reverse 25 11 2 0 2.5"},
+			},
+		},
+		"Exceeded reversal must be reported": {
+			src: Src{
+				method:  http.MethodPost,
+				path:    "/accounts/1/reversals",
+				body:    `{"uid":26,"original":13,"dst":2}`,
+				manager: managerMock{err: domain.ErrReversalExceeded},
+			},
+			dst: Dst{
+				code:  http.StatusConflict,
+				body:  `{"Status":3,"Error":{"Code":"reversal_exceeded","Message":"amount exceeds the rest of the original operation","Retryable":false,"Uid":26}}`,
+				calls: []string{"reverse 26 13 1 2 0"},
+			},
+		},
+		"Escrow must be opened": {
+			src: Src{
+				method: http.MethodPost,
+				path:   "/escrows/20/open",
+				body:   `{"uid":21,"account":1,"amount":30}`,
+			},
+			dst: Dst{
+				code:  http.StatusOK,
+				body:  `{"Status":0}`,
+				calls: []string{"escrow.open 21 20 1 30"},
+			},
+		},
+		"Escrow must be released to the sellers": {
+			src: Src{
+				method: http.MethodPost,
+				path:   "/escrows/20/release",
+				body:   `{"uid":22,"account":1,"payouts":[{"account":2,"amount":10},{"account":3,"amount":5}]}`,
+			},
+			dst: Dst{
+				code:  http.StatusOK,
+				body:  `{"Status":0}`,
+				calls: []string{"escrow.release 22 20 1 [{2 10} {3 5}]"},
+			},
+		},
+		"Duplicated seller must be rejected": {
+			src: Src{
+				method: http.MethodPost,
+				path:   "/escrows/20/release",
+				body:   `{"uid":23,"account":1,"payouts":[{"account":2,"amount":10},{"account":2,"amount":5}]}`,
+			},
+			dst: Dst{
+				code: http.StatusBadRequest,
+				body: `{"Status":5,"Error":{"Code":"duplicate_payout","Message":"seller must be paid once per operation","Retryable":false,"Uid":23}}`,
+			},
+		},
+		"Escrow must be refunded": {
+			src: Src{
+				method: http.MethodPost,
+				path:   "/escrows/20/refund",
+				body:   `{"uid":24,"account":1,"amount":5}`,
+			},
+			dst: Dst{
+				code:  http.StatusOK,
+				body:  `{"Status":0}`,
+				calls: []string{"escrow.refund 24 20 1 5"},
+			},
+		},
+		"Escrow must be returned": {
+			src: Src{
+				method: http.MethodGet,
+				path:   "/escrows/20?account=1",
+			},
+			dst: Dst{
+				code:  http.StatusOK,
+				body:  `{"Status":0,"Escrow":{"Id":20,"Account":1,"Amount":30,"Balance":10,"Created":"0001-01-01T00:00:00Z"}}`,
+				calls: []string{"escrow.get 20 1"},
+			},
+		},
+		"Wrong method must be rejected": {
+			src: Src{
+				method: http.MethodGet,
+				path:   "/accounts/1/credit",
+			},
+			dst: Dst{
+				code: http.StatusMethodNotAllowed,
+				body: "Method Not Allowed",
+			},
+		},
+		"Invalid account must not be routed": {
+			src: Src{
+				method: http.MethodPost,
+				path:   "/accounts/abc/credit",
+				body:   `{"uid":1,"amount":1}`,
+			},
+			dst: Dst{
+				code: http.StatusNotFound,
+				body: "404 page not found",
+			},
+		},
+		"Unknown route must not be found": {
+			src: Src{
+				method: http.MethodPost,
+				path:   "/accounts/1/unknown",
+			},
+			dst: Dst{
+				code: http.StatusNotFound,
+				body: "404 page not found",
+			},
+		},
+	}
+
+	ctx := context.Background()
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			manager := test.src.manager
+			h := NewHttpHandler(ctx, &manager, &manager, slog.New(slog.DiscardHandler))
+
+			req := httptest.NewRequest(test.src.method, test.src.path, strings.NewReader(test.src.body))
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			assert.Equal(t, test.dst.code, w.Code)
+			assert.Equal(t, test.dst.body, strings.TrimSpace(w.Body.String()))
+			assert.Equal(t, test.dst.calls, manager.calls)
+		})
+	}
+}
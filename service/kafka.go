@@ -0,0 +1,364 @@
+package service
+
+import (
+	"billing/domain"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/IBM/sarama"
+	"log/slog"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Header of the response with topic of the command
+const kafkaSubjectHeader = "subject"
+
+const kafkaRetryDelay = time.Second
+
+type kafkaProducer interface {
+	SendMessage(msg *sarama.ProducerMessage) (partition int32, offset int64, err error)
+	Close() error
+}
+
+type kafkaMessage struct {
+	transport *kafkaTransport
+	msg       *sarama.ConsumerMessage
+}
+
+func (m *kafkaMessage) Subject() string {
+	return m.msg.Topic
+}
+
+func (m *kafkaMessage) Data() []byte {
+	return m.msg.Value
+}
+
+// Offset is committed after the handler, so the message can not be handled in background
+func (m *kafkaMessage) Synchronous() {}
+
+// Reply into the result topic with the account of the command as key.
+// Headers of the command are copied to the response.
+func (m *kafkaMessage) Reply(data []byte) error {
+	return m.ReplyWithHeaders(data, nil)
+}
+
+func (m *kafkaMessage) Headers() map[string]string {
+	headers := make(map[string]string, len(m.msg.Headers))
+	for _, header := range m.msg.Headers {
+		if header != nil {
+			headers[string(header.Key)] = string(header.Value)
+		}
+	}
+	return headers
+}
+
+// Reply into the result topic with the account of the command as key.
+// Headers of the command are copied to the response, unless they are overridden.
+func (m *kafkaMessage) ReplyWithHeaders(data []byte, headers map[string]string) error {
+	records := make([]sarama.RecordHeader, 0, len(m.msg.Headers)+len(headers)+1)
+	records = append(records, sarama.RecordHeader{
+		Key:   []byte(kafkaSubjectHeader),
+		Value: []byte(m.msg.Topic),
+	})
+	for _, header := range m.msg.Headers {
+		if header == nil || string(header.Key) == kafkaSubjectHeader {
+			continue
+		}
+		if _, ok := headers[string(header.Key)]; ok {
+			continue
+		}
+		records = append(records, *header)
+	}
+	records = append(records, kafkaHeaders(headers)...)
+
+	msg := &sarama.ProducerMessage{
+		Topic:   m.transport.options.ResultTopic,
+		Value:   sarama.ByteEncoder(data),
+		Headers: records,
+	}
+	if key := kafkaKey(m.msg); key != nil {
+		msg.Key = key
+	}
+
+	_, _, err := m.transport.producer.SendMessage(msg)
+	return err
+}
+
+// Get key of the result of the command: account of the command (source of the transfer).
+// Key of the command is used, if the command has no account (e.g. it is malformed).
+func kafkaKey(msg *sarama.ConsumerMessage) sarama.Encoder {
+	var command struct {
+		Account uint32
+		Src     uint32
+	}
+	if json.Unmarshal(msg.Value, &command) == nil {
+		if command.Account != 0 {
+			return sarama.StringEncoder(strconv.FormatUint(uint64(command.Account), 10))
+		}
+		if command.Src != 0 {
+			return sarama.StringEncoder(strconv.FormatUint(uint64(command.Src), 10))
+		}
+	}
+	if msg.Key != nil {
+		return sarama.ByteEncoder(msg.Key)
+	}
+	return nil
+}
+
+// Convert headers into the records ordered by key
+func kafkaHeaders(headers map[string]string) []sarama.RecordHeader {
+	keys := make([]string, 0, len(headers))
+	for key := range headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	records := make([]sarama.RecordHeader, 0, len(keys))
+	for _, key := range keys {
+		records = append(records, sarama.RecordHeader{
+			Key:   []byte(key),
+			Value: []byte(headers[key]),
+		})
+	}
+	return records
+}
+
+// Handler of the consumer group session
+type kafkaConsumer struct {
+	transport *kafkaTransport
+	handler   Handler
+}
+
+func (c *kafkaConsumer) Setup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+func (c *kafkaConsumer) Cleanup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+// Handle messages of the claim one by one.
+// Offset is committed only after the message is completely processed,
+// so unprocessed messages are redelivered after the crash or rebalance.
+func (c *kafkaConsumer) ConsumeClaim(
+	session sarama.ConsumerGroupSession,
+	claim sarama.ConsumerGroupClaim,
+) error {
+	for msg := range claim.Messages() {
+		c.handler(&kafkaMessage{transport: c.transport, msg: msg})
+		session.MarkMessage(msg, "")
+		session.Commit()
+	}
+	return nil
+}
+
+type kafkaTransport struct {
+	options  domain.KafkaOptions
+	config   *sarama.Config
+	client   sarama.Client
+	producer kafkaProducer
+	logger   *slog.Logger
+	ctx      context.Context
+	cancel   context.CancelFunc
+	mu       sync.Mutex
+	groups   []sarama.ConsumerGroup
+	wg       sync.WaitGroup
+}
+
+// Consume topic as a member of the consumer group (queue)
+func (t *kafkaTransport) Subscribe(
+	subject, queue string,
+	handler Handler,
+) error {
+	group, err := sarama.NewConsumerGroup(t.options.Brokers, queue, t.config)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	t.groups = append(t.groups, group)
+	t.mu.Unlock()
+
+	consumer := &kafkaConsumer{
+		transport: t,
+		handler:   handler,
+	}
+
+	t.wg.Add(2)
+	go func() {
+		defer t.wg.Done()
+		for err := range group.Errors() {
+			t.logger.Error("Kafka consumer group failed", "topic", subject, "error", err)
+		}
+	}()
+	go func() {
+		defer t.wg.Done()
+		t.consume(group, subject, consumer)
+	}()
+
+	return nil
+}
+
+// Consume topic until the transport is closed.
+// Consume returns on every rebalance, so it must be called in the loop.
+func (t *kafkaTransport) consume(
+	group sarama.ConsumerGroup,
+	topic string,
+	consumer *kafkaConsumer,
+) {
+	for {
+		err := group.Consume(t.ctx, []string{topic}, consumer)
+		if t.ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			t.logger.Error("Kafka consumption failed", "topic", topic, "error", err)
+			select {
+			case <-t.ctx.Done():
+				return
+			case <-time.After(kafkaRetryDelay):
+			}
+		}
+	}
+}
+
+func (t *kafkaTransport) Publish(subject string, data []byte) error {
+	return t.PublishWithHeaders(subject, data, nil)
+}
+
+func (t *kafkaTransport) PublishWithHeaders(subject string, data []byte, headers map[string]string) error {
+	msg := &sarama.ProducerMessage{
+		Topic: subject,
+		Value: sarama.ByteEncoder(data),
+	}
+	if len(headers) != 0 {
+		msg.Headers = kafkaHeaders(headers)
+	}
+
+	_, _, err := t.producer.SendMessage(msg)
+	return err
+}
+
+// Leave consumer groups.
+// Closing of the group waits for the claims, so the current messages are handled and committed.
+func (t *kafkaTransport) Drain() error {
+	t.cancel()
+
+	t.mu.Lock()
+	groups := t.groups
+	t.groups = nil
+	t.mu.Unlock()
+
+	for _, group := range groups {
+		err := group.Close()
+		if err != nil {
+			t.logger.Error("Kafka consumer group is not closed", "error", err)
+		}
+	}
+
+	t.wg.Wait()
+	return nil
+}
+
+// Check connectivity to the cluster by refresh of the metadata of the result topic
+func (t *kafkaTransport) Check(ctx context.Context) error {
+	if t.client.Closed() {
+		return errors.New("Kafka client is closed")
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- t.client.RefreshMetadata(t.options.ResultTopic)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("Kafka is unavailable: %v", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (t *kafkaTransport) Close() error {
+	err := t.Drain()
+	if e := t.producer.Close(); err == nil {
+		err = e
+	}
+	if t.client != nil {
+		if e := t.client.Close(); err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+func newKafkaTransport(
+	options domain.KafkaOptions,
+	config *sarama.Config,
+	client sarama.Client,
+	producer kafkaProducer,
+	logger *slog.Logger,
+) *kafkaTransport {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &kafkaTransport{
+		options:  options,
+		config:   config,
+		client:   client,
+		producer: producer,
+		logger:   logger,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Create transport over the Kafka cluster.
+// Commands are consumed from the topics with the names of the subjects.
+// Responses are produced into the result topic keyed by account of the command,
+// so results of the account keep their order regardless of the keys of the commands.
+func NewKafkaTransport(
+	options domain.KafkaOptions,
+	logger *slog.Logger,
+) (Transport, error) {
+	config, err := kafkaConfig(options)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := sarama.NewClient(options.Brokers, config)
+	if err != nil {
+		return nil, err
+	}
+
+	producer, err := sarama.NewSyncProducerFromClient(client)
+	if err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+
+	return newKafkaTransport(options, config, client, producer, logger), nil
+}
+
+// Configuration of the client: offsets are committed manually after the handling
+func kafkaConfig(options domain.KafkaOptions) (*sarama.Config, error) {
+	version, err := sarama.ParseKafkaVersion(options.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	config := sarama.NewConfig()
+	config.ClientID = options.ClientId
+	config.Version = version
+	config.Producer.RequiredAcks = sarama.WaitForAll
+	config.Producer.Return.Successes = true
+	config.Consumer.Return.Errors = true
+	config.Consumer.Offsets.Initial = sarama.OffsetOldest
+	config.Consumer.Offsets.AutoCommit.Enable = false
+	return config, nil
+}
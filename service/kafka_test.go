@@ -0,0 +1,259 @@
+package service
+
+import (
+	"billing/domain"
+	"context"
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+// Start in-process broker with the single-partition topic, that is consumed by the group with the same name
+func newKafkaBroker(t *testing.T, topic string, values ...string) *sarama.MockBroker {
+	broker := sarama.NewMockBroker(t, 0)
+
+	fetch := sarama.NewMockFetchResponse(t, 1)
+	for offset, value := range values {
+		fetch.SetMessageWithKey(topic, 0, int64(offset), sarama.StringEncoder("producer-key"), sarama.StringEncoder(value))
+	}
+
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"ApiVersionsRequest": sarama.NewMockApiVersionsResponse(t),
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader(topic, 0, broker.BrokerID()),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetOffset(topic, 0, sarama.OffsetOldest, 0).
+			SetOffset(topic, 0, sarama.OffsetNewest, int64(len(values))),
+		"FindCoordinatorRequest": sarama.NewMockFindCoordinatorResponse(t).
+			SetCoordinator(sarama.CoordinatorGroup, topic, broker),
+		"JoinGroupRequest": sarama.NewMockJoinGroupResponse(t).
+			SetGroupProtocol(sarama.RangeBalanceStrategyName),
+		"SyncGroupRequest": sarama.NewMockSyncGroupResponse(t).
+			SetMemberAssignment(&sarama.ConsumerGroupMemberAssignment{
+				Topics: map[string][]int32{topic: {0}},
+			}),
+		"OffsetFetchRequest": sarama.NewMockOffsetFetchResponse(t).
+			SetOffset(topic, topic, 0, 0, "", sarama.ErrNoError).
+			SetError(sarama.ErrNoError),
+		"HeartbeatRequest":    sarama.NewMockHeartbeatResponse(t),
+		"OffsetCommitRequest": sarama.NewMockOffsetCommitResponse(t),
+		"LeaveGroupRequest":   sarama.NewMockLeaveGroupResponse(t),
+		"FetchRequest":        fetch,
+	})
+	return broker
+}
+
+// Check, that the broker received commit of the offset
+func kafkaCommitted(broker *sarama.MockBroker) bool {
+	for _, rr := range broker.History() {
+		if _, ok := rr.Request.(*sarama.OffsetCommitRequest); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Create producer, that sends messages into the channel
+func newKafkaProducer(t *testing.T, config *sarama.Config, count int) (*mocks.SyncProducer, chan *sarama.ProducerMessage) {
+	producer := mocks.NewSyncProducer(t, config)
+	messages := make(chan *sarama.ProducerMessage, count)
+	for i := 0; i < count; i++ {
+		producer.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(msg *sarama.ProducerMessage) error {
+			messages <- msg
+			return nil
+		})
+	}
+	return producer, messages
+}
+
+func TestKafkaTransport_Subscribe(t *testing.T) {
+	broker := newKafkaBroker(t, "bank.credit", `{"uid":1,"account":7,"amount":10}`)
+	defer broker.Close()
+
+	options := domain.KafkaOptions{
+		Brokers:     []string{broker.Addr()},
+		Version:     "2.0.0",
+		ClientId:    "billing",
+		ResultTopic: "bank.result",
+	}
+	config, err := kafkaConfig(options)
+	require.NoError(t, err)
+	producer, results := newKafkaProducer(t, config, 1)
+	logger := slog.New(slog.DiscardHandler)
+	transport := newKafkaTransport(options, config, nil, producer, logger)
+
+	manager := &managerMock{err: domain.ErrNoMoney}
+	s := &server{
+		transport: transport,
+		manager:   manager,
+		logger:    logger,
+	}
+	handler := endpoints(manager)["credit"]
+	committed := make(chan bool, 1)
+	err = transport.Subscribe("bank.credit", "bank.credit", func(msg Message) {
+		s.handle(context.Background(), handler, msg)
+		committed <- kafkaCommitted(broker)
+	})
+	require.NoError(t, err)
+
+	var result *sarama.ProducerMessage
+	select {
+	case result = <-results:
+	case <-time.After(10 * time.Second):
+		t.Fatal("result must be produced")
+	}
+	assert.False(t, <-committed, "offset must not be committed before the command is handled")
+	assert.Eventually(t, func() bool {
+		return kafkaCommitted(broker)
+	}, 10*time.Second, 10*time.Millisecond, "offset must be committed after the command is handled")
+	require.NoError(t, transport.Close())
+
+	assert.Equal(t, []string{"credit 1 7 10"}, manager.calls)
+	assert.Equal(t, "bank.result", result.Topic)
+	assert.Equal(t, sarama.StringEncoder("7"), result.Key)
+	assert.Equal(t, []sarama.RecordHeader{
+		{Key: []byte("subject"), Value: []byte("bank.credit")},
+	}, result.Headers)
+	value, err := result.Value.Encode()
+	require.NoError(t, err)
+	assert.Contains(t, string(value), `"Status":3`)
+}
+
+func TestKafkaTransport_Publish(t *testing.T) {
+	config := mocks.NewTestConfig()
+	producer, messages := newKafkaProducer(t, config, 1)
+	transport := newKafkaTransport(
+		domain.KafkaOptions{ResultTopic: "bank.result"},
+		config,
+		nil,
+		producer,
+		slog.New(slog.DiscardHandler),
+	)
+
+	err := transport.Publish("bank.dead", []byte("letter"))
+	require.NoError(t, err)
+	assert.NoError(t, transport.Close())
+
+	msg := <-messages
+	assert.Equal(t, "bank.dead", msg.Topic)
+	assert.Nil(t, msg.Key)
+	assert.Equal(t, sarama.ByteEncoder("letter"), msg.Value)
+}
+
+func TestKafkaMessage_ReplyWithHeaders(t *testing.T) {
+	config := mocks.NewTestConfig()
+	producer, messages := newKafkaProducer(t, config, 1)
+	transport := newKafkaTransport(
+		domain.KafkaOptions{ResultTopic: "bank.result"},
+		config,
+		nil,
+		producer,
+		slog.New(slog.DiscardHandler),
+	)
+	defer transport.Close()
+
+	msg := &kafkaMessage{
+		transport: transport,
+		msg: &sarama.ConsumerMessage{
+			Topic: "bank.credit",
+			Headers: []*sarama.RecordHeader{
+				{Key: []byte("trace"), Value: []byte("abc")},
+				{Key: []byte("traceparent"), Value: []byte("caller")},
+			},
+		},
+	}
+	assert.Equal(t, map[string]string{
+		"trace":       "abc",
+		"traceparent": "caller",
+	}, msg.Headers())
+
+	err := msg.ReplyWithHeaders([]byte("{}"), map[string]string{
+		"traceparent": "service",
+		"tracestate":  "state",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []sarama.RecordHeader{
+		{Key: []byte("subject"), Value: []byte("bank.credit")},
+		{Key: []byte("trace"), Value: []byte("abc")},
+		{Key: []byte("traceparent"), Value: []byte("service")},
+		{Key: []byte("tracestate"), Value: []byte("state")},
+	}, (<-messages).Headers)
+}
+
+func TestKafkaKey(t *testing.T) {
+	type Src struct {
+		key   []byte
+		value string
+	}
+
+	type Test struct {
+		src Src
+		dst sarama.Encoder
+	}
+
+	tests := map[string]Test{
+		"Account must be used as key": {
+			src: Src{key: []byte("producer-key"), value: `{"uid":1,"account":7,"amount":10}`},
+			dst: sarama.StringEncoder("7"),
+		},
+		"Source of the transfer must be used as key": {
+			src: Src{value: `{"uid":1,"src":3,"dst":4,"amount":10}`},
+			dst: sarama.StringEncoder("3"),
+		},
+		"Key of the malformed command must be used": {
+			src: Src{key: []byte("producer-key"), value: `{"uid":`},
+			dst: sarama.ByteEncoder("producer-key"),
+		},
+		"Command without account and key must be not keyed": {
+			src: Src{value: `{"uid":1}`},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			key := kafkaKey(&sarama.ConsumerMessage{Key: test.src.key, Value: []byte(test.src.value)})
+			assert.Equal(t, test.dst, key)
+		})
+	}
+}
+
+func TestKafkaTransport_Check(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 0)
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"ApiVersionsRequest": sarama.NewMockApiVersionsResponse(t),
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("bank.result", 0, broker.BrokerID()),
+	})
+
+	options := domain.KafkaOptions{
+		Brokers:     []string{broker.Addr()},
+		Version:     "2.0.0",
+		ClientId:    "billing",
+		ResultTopic: "bank.result",
+	}
+	config, err := kafkaConfig(options)
+	require.NoError(t, err)
+	config.Metadata.Retry.Max = 0
+	client, err := sarama.NewClient(options.Brokers, config)
+	require.NoError(t, err)
+	producer := mocks.NewSyncProducer(t, config)
+	transport := newKafkaTransport(options, config, client, producer, slog.New(slog.DiscardHandler))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	assert.NoError(t, transport.Check(ctx), "available cluster must be reported")
+
+	broker.Close()
+	assert.Error(t, transport.Check(ctx), "unavailable cluster must be reported")
+
+	require.NoError(t, transport.Close())
+	assert.EqualError(t, transport.Check(ctx), "Kafka client is closed")
+}
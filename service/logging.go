@@ -0,0 +1,129 @@
+package service
+
+import (
+	"billing/domain"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Create logger with the configured level and format.
+// Level can be changed at runtime by the variable (nil for fixed level).
+func NewLogger(
+	w io.Writer,
+	options domain.LogOptions,
+	level *slog.LevelVar,
+) (*slog.Logger, error) {
+	l, err := ParseLogLevel(options.Level)
+	if err != nil {
+		return nil, err
+	}
+	if level == nil {
+		level = new(slog.LevelVar)
+	}
+	level.Set(l)
+
+	opts := &slog.HandlerOptions{Level: level}
+	switch options.Format {
+	case "", "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	}
+
+	return nil, fmt.Errorf("unknown log format %q", options.Format)
+}
+
+// Parse level of the log messages: debug, info, warning or error
+func ParseLogLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+
+	return 0, fmt.Errorf("unknown log level %q", level)
+}
+
+// Get log fields of the request: uid, correlation id, accounts and amount
+func requestAttrs(r Request) []any {
+	header := r.Header()
+	attrs := []any{"uid", header.Uid}
+	if header.CorrelationId != "" {
+		attrs = append(attrs, "correlation_id", header.CorrelationId)
+	}
+
+	switch r := r.(type) {
+	case *CreditRequest:
+		attrs = append(attrs, "accounts", []uint32{r.Account}, "amount", r.Amount)
+	case *DebitRequest:
+		attrs = append(attrs, "accounts", []uint32{r.Account}, "amount", r.Amount)
+	case *TransferRequest:
+		attrs = append(attrs, "accounts", []uint32{r.Src, r.Dst}, "amount", r.Amount)
+	case *DepositRequest:
+		attrs = append(attrs, "peer", r.Peer, "accounts", []uint32{r.Account}, "amount", r.Amount)
+	case *AcquireRequest:
+		accounts := []uint32{r.Account}
+		if r.Dst != 0 {
+			accounts = append(accounts, r.Dst)
+		}
+		attrs = append(attrs, "accounts", accounts, "amount", r.Amount)
+	case *CommitRequest:
+		attrs = append(attrs, "accounts", []uint32{r.Account})
+	case *ReverseRequest:
+		accounts := []uint32{r.Account}
+		if r.Dst != 0 {
+			accounts = append(accounts, r.Dst)
+		}
+		attrs = append(attrs, "original", r.Original, "accounts", accounts, "amount", r.Amount)
+	case *EscrowOpenRequest:
+		attrs = append(attrs, "escrow", r.Escrow, "accounts", []uint32{r.Account}, "amount", r.Amount)
+	case *EscrowReleaseRequest:
+		accounts := []uint32{r.Account}
+		var amount float32
+		for _, payout := range r.Payouts {
+			accounts = append(accounts, payout.Account)
+			amount += payout.Amount
+		}
+		attrs = append(attrs, "escrow", r.Escrow, "accounts", accounts, "amount", amount)
+	case *EscrowRefundRequest:
+		attrs = append(attrs, "escrow", r.Escrow, "accounts", []uint32{r.Account}, "amount", r.Amount)
+	case *RollbackRequest:
+		attrs = append(attrs, "accounts", []uint32{r.Account})
+	}
+
+	return attrs
+}
+
+// Log result of the operation with its status and duration.
+// Unexpected errors are logged with level error, expected ones (e.g. no money) with level info.
+func logResult(
+	ctx context.Context,
+	logger *slog.Logger,
+	err error,
+	response Response,
+	start time.Time,
+	attrs ...any,
+) {
+	level := slog.LevelInfo
+	attrs = append(attrs, "status", response.Status, "duration", time.Since(start))
+	if err != nil {
+		if response.Error != nil {
+			attrs = append(attrs, "code", response.Error.Code)
+		}
+		attrs = append(attrs, "error", err.Error())
+		if _, known := domain.DescribeError(err); !known {
+			level = slog.LevelError
+		}
+	}
+
+	logger.Log(ctx, level, "Operation is processed", attrs...)
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"billing/domain"
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestNewLogger(t *testing.T) {
+	type Src struct {
+		options domain.LogOptions
+	}
+
+	type Dst struct {
+		err  bool
+		json bool
+	}
+
+	type Test struct {
+		src Src
+		dst Dst
+	}
+
+	tests := map[string]Test{
+		"Text format must be used by default": {
+			src: Src{
+				options: domain.LogOptions{},
+			},
+		},
+		"JSON format must be supported": {
+			src: Src{
+				options: domain.LogOptions{Level: "warning", Format: "json"},
+			},
+			dst: Dst{
+				json: true,
+			},
+		},
+		"Unknown level must be rejected": {
+			src: Src{
+				options: domain.LogOptions{Level: "verbose"},
+			},
+			dst: Dst{
+				err: true,
+			},
+		},
+		"Unknown format must be rejected": {
+			src: Src{
+				options: domain.LogOptions{Format: "xml"},
+			},
+			dst: Dst{
+				err: true,
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger, err := NewLogger(&buf, test.src.options, nil)
+			if test.dst.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			logger.Error("failure", "uid", 1)
+			assert.Equal(t, test.dst.json, json.Valid(buf.Bytes()))
+			assert.Contains(t, buf.String(), "uid")
+		})
+	}
+}
+
+func TestExecuteLogging(t *testing.T) {
+	type Src struct {
+		manager managerMock
+		level   string
+	}
+
+	type Dst struct {
+		fields map[string]interface{}
+		stack  bool
+		empty  bool
+	}
+
+	type Test struct {
+		src Src
+		dst Dst
+	}
+
+	tests := map[string]Test{
+		"Result of the operation must be logged with the fields of the request": {
+			src: Src{
+				level: "info",
+			},
+			dst: Dst{
+				fields: map[string]interface{}{
+					"level":          "INFO",
+					"subject":        "bank.transfer",
+					"uid":            float64(1),
+					"correlation_id": "c1",
+					"accounts":       []interface{}{float64(1), float64(2)},
+					"amount":         float64(10),
+					"status":         float64(domain.StatusOk),
+				},
+			},
+		},
+		"Expected error must be logged with its code": {
+			src: Src{
+				manager: managerMock{err: domain.ErrNoMoney},
+				level:   "info",
+			},
+			dst: Dst{
+				fields: map[string]interface{}{
+					"level":  "INFO",
+					"status": float64(domain.StatusNoMoney),
+					"code":   domain.ErrorCodeNoMoney,
+					"error":  domain.ErrNoMoney.Error(),
+				},
+			},
+		},
+		"Panic must be logged with the stack trace": {
+			src: Src{
+				manager: managerMock{panic: "boom"},
+				level:   "error",
+			},
+			dst: Dst{
+				fields: map[string]interface{}{
+					"level":  "ERROR",
+					"uid":    float64(1),
+					"status": float64(domain.StatusUnknownError),
+					"code":   domain.ErrorCodeUnknown,
+					"error":  "panic: boom",
+				},
+				stack: true,
+			},
+		},
+		"Operations must not be logged below the configured level": {
+			src: Src{
+				level: "warning",
+			},
+			dst: Dst{
+				empty: true,
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger, err := NewLogger(&buf, domain.LogOptions{Level: test.src.level, Format: "json"}, nil)
+			require.NoError(t, err)
+
+			handler := endpoints(&test.src.manager)["transfer"]
+			r := &TransferRequest{Uid: 1, Src: 1, Dst: 2, Amount: 10, CorrelationId: "c1"}
+			_, _ = execute(context.Background(), handler, r, logger.With("subject", "bank.transfer"))
+
+			if test.dst.empty {
+				assert.Empty(t, buf.String())
+				return
+			}
+
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			require.Len(t, lines, 1)
+
+			var record map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(lines[0]), &record))
+			for key, value := range test.dst.fields {
+				assert.Equal(t, value, record[key], key)
+			}
+			assert.Contains(t, record, "duration")
+			assert.Equal(t, test.dst.stack, record["stack"] != nil)
+		})
+	}
+}
@@ -0,0 +1,466 @@
+package service
+
+import (
+	"billing/domain"
+	"billing/manager/banker"
+	"context"
+	std "database/sql"
+	"github.com/adverax/echo/database/sql"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"time"
+)
+
+const metricsNamespace = "billing"
+
+// Timeout of the queries of the metrics collectors
+const metricsQueryTimeout = 5 * time.Second
+
+// Collectors of the service metrics
+type Metrics struct {
+	registry     *prometheus.Registry
+	operations   *prometheus.CounterVec
+	latency      *prometheus.HistogramVec
+	transactions prometheus.Histogram
+}
+
+// Register additional collector
+func (m *Metrics) Register(collector prometheus.Collector) error {
+	return m.registry.Register(collector)
+}
+
+// Register collector of the shard. Metrics of the named shard are labeled by its name.
+func (m *Metrics) RegisterShard(shard string, collector prometheus.Collector) error {
+	if shard == "" {
+		return m.Register(collector)
+	}
+	return prometheus.WrapRegistererWith(prometheus.Labels{"shard": shard}, m.registry).Register(collector)
+}
+
+// Get handler, that exposes metrics by the path /metrics
+func (m *Metrics) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+	return mux
+}
+
+func (m *Metrics) observe(
+	operation string,
+	start time.Time,
+	err error,
+) {
+	code := "ok"
+	if err != nil {
+		info, _ := domain.DescribeError(err)
+		code = info.Code
+	}
+
+	m.operations.WithLabelValues(operation, code).Inc()
+	m.latency.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+// Create collectors of the service and runtime metrics in the registry
+func NewMetrics(registry *prometheus.Registry) (*Metrics, error) {
+	m := &Metrics{
+		registry: registry,
+		operations: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: metricsNamespace,
+				Name:      "operations_total",
+				Help:      "Count of the banker operations by result code.",
+			},
+			[]string{"operation", "code"},
+		),
+		latency: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: metricsNamespace,
+				Name:      "operation_duration_seconds",
+				Help:      "Duration of the banker operations.",
+				Buckets:   prometheus.DefBuckets,
+			},
+			[]string{"operation"},
+		),
+		transactions: prometheus.NewHistogram(
+			prometheus.HistogramOpts{
+				Namespace: metricsNamespace,
+				Name:      "db_transaction_duration_seconds",
+				Help:      "Duration of the database transactions of the banker.",
+				Buckets:   prometheus.DefBuckets,
+			},
+		),
+	}
+
+	collectors := []prometheus.Collector{
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		m.operations,
+		m.latency,
+		m.transactions,
+	}
+	for _, collector := range collectors {
+		err := registry.Register(collector)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// Banker, that counts operations and measures their duration
+type metricsManager struct {
+	banker.Manager
+	metrics *Metrics
+}
+
+func (m *metricsManager) Credit(
+	ctx context.Context,
+	uid int64,
+	account uint32,
+	amount float32,
+) error {
+	start := time.Now()
+	err := m.Manager.Credit(ctx, uid, account, amount)
+	m.metrics.observe("credit", start, err)
+	return err
+}
+
+func (m *metricsManager) Debit(
+	ctx context.Context,
+	uid int64,
+	account uint32,
+	amount float32,
+) error {
+	start := time.Now()
+	err := m.Manager.Debit(ctx, uid, account, amount)
+	m.metrics.observe("debit", start, err)
+	return err
+}
+
+func (m *metricsManager) Transfer(
+	ctx context.Context,
+	uid int64,
+	src, dst uint32,
+	amount float32,
+) error {
+	start := time.Now()
+	err := m.Manager.Transfer(ctx, uid, src, dst, amount)
+	m.metrics.observe("transfer", start, err)
+	return err
+}
+
+func (m *metricsManager) Deposit(
+	ctx context.Context,
+	peer string,
+	uid int64,
+	account uint32,
+	amount float32,
+) error {
+	start := time.Now()
+	err := m.Manager.Deposit(ctx, peer, uid, account, amount)
+	m.metrics.observe("deposit", start, err)
+	return err
+}
+
+func (m *metricsManager) Acquire(
+	ctx context.Context,
+	uid int64,
+	account uint32,
+	dst uint32,
+	amount float32,
+) error {
+	start := time.Now()
+	err := m.Manager.Acquire(ctx, uid, account, dst, amount)
+	m.metrics.observe("acquire", start, err)
+	return err
+}
+
+func (m *metricsManager) Commit(
+	ctx context.Context,
+	uid int64,
+	account uint32,
+) error {
+	start := time.Now()
+	err := m.Manager.Commit(ctx, uid, account)
+	m.metrics.observe("commit", start, err)
+	return err
+}
+
+func (m *metricsManager) Rollback(
+	ctx context.Context,
+	uid int64,
+	account uint32,
+) error {
+	start := time.Now()
+	err := m.Manager.Rollback(ctx, uid, account)
+	m.metrics.observe("rollback", start, err)
+	return err
+}
+
+func (m *metricsManager) Reverse(
+	ctx context.Context,
+	reversal *domain.Reversal,
+) error {
+	start := time.Now()
+	err := m.Manager.Reverse(ctx, reversal)
+	m.metrics.observe("reverse", start, err)
+	return err
+}
+
+func (m *metricsManager) Balance(
+	ctx context.Context,
+	account uint32,
+) (float32, error) {
+	start := time.Now()
+	amount, err := m.Manager.Balance(ctx, account)
+	m.metrics.observe("balance", start, err)
+	return amount, err
+}
+
+func (m *metricsManager) History(
+	ctx context.Context,
+	account uint32,
+	offset, limit int,
+) ([]*domain.HistoryRecord, error) {
+	start := time.Now()
+	records, err := m.Manager.History(ctx, account, offset, limit)
+	m.metrics.observe("history", start, err)
+	return records, err
+}
+
+// Decorate banker with metrics of the operations
+func NewMetricsManager(
+	manager banker.Manager,
+	metrics *Metrics,
+) banker.Manager {
+	return &metricsManager{
+		Manager: manager,
+		metrics: metrics,
+	}
+}
+
+// Escrow, that counts operations and measures their duration
+type metricsEscrow struct {
+	banker.Escrow
+	metrics *Metrics
+}
+
+func (m *metricsEscrow) Open(
+	ctx context.Context,
+	uid int64,
+	id int64,
+	account uint32,
+	amount float32,
+) error {
+	start := time.Now()
+	err := m.Escrow.Open(ctx, uid, id, account, amount)
+	m.metrics.observe("escrow.open", start, err)
+	return err
+}
+
+func (m *metricsEscrow) Release(
+	ctx context.Context,
+	uid int64,
+	id int64,
+	account uint32,
+	payouts []domain.Payout,
+) error {
+	start := time.Now()
+	err := m.Escrow.Release(ctx, uid, id, account, payouts)
+	m.metrics.observe("escrow.release", start, err)
+	return err
+}
+
+func (m *metricsEscrow) Refund(
+	ctx context.Context,
+	uid int64,
+	id int64,
+	account uint32,
+	amount float32,
+) error {
+	start := time.Now()
+	err := m.Escrow.Refund(ctx, uid, id, account, amount)
+	m.metrics.observe("escrow.refund", start, err)
+	return err
+}
+
+func (m *metricsEscrow) Get(
+	ctx context.Context,
+	id int64,
+	account uint32,
+) (*domain.Escrow, []*domain.EscrowRecord, error) {
+	start := time.Now()
+	escrow, records, err := m.Escrow.Get(ctx, id, account)
+	m.metrics.observe("escrow.get", start, err)
+	return escrow, records, err
+}
+
+// Decorate escrow with metrics of the operations
+func NewMetricsEscrow(
+	escrow banker.Escrow,
+	metrics *Metrics,
+) banker.Escrow {
+	return &metricsEscrow{
+		Escrow:  escrow,
+		metrics: metrics,
+	}
+}
+
+// Repository, that measures duration of the transactions
+type metricsRepository struct {
+	sql.Repository
+	metrics *Metrics
+}
+
+func (r *metricsRepository) Transaction(
+	ctx context.Context,
+	action func(ctx context.Context) error,
+) error {
+	start := time.Now()
+	err := r.Repository.Transaction(ctx, action)
+	r.metrics.transactions.Observe(time.Since(start).Seconds())
+	return err
+}
+
+// Decorate repository with metrics of the transactions
+func NewMetricsRepository(
+	repository sql.Repository,
+	metrics *Metrics,
+) sql.Repository {
+	return &metricsRepository{
+		Repository: repository,
+		metrics:    metrics,
+	}
+}
+
+type holdsSummary interface {
+	Summary(ctx context.Context) (count int64, amount float32, err error)
+}
+
+// Collector of the active holds. Holds are counted by the database on every scrape.
+type holdsCollector struct {
+	assets holdsSummary
+	count  *prometheus.Desc
+	amount *prometheus.Desc
+}
+
+func (c *holdsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.count
+	ch <- c.amount
+}
+
+func (c *holdsCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), metricsQueryTimeout)
+	defer cancel()
+
+	count, amount, err := c.assets.Summary(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.count, err)
+		ch <- prometheus.NewInvalidMetric(c.amount, err)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.count, prometheus.GaugeValue, float64(count))
+	ch <- prometheus.MustNewConstMetric(c.amount, prometheus.GaugeValue, float64(amount))
+}
+
+func NewHoldsCollector(assets holdsSummary) prometheus.Collector {
+	return &holdsCollector{
+		assets: assets,
+		count: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, "", "holds_active"),
+			"Count of the active holds.",
+			nil, nil,
+		),
+		amount: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, "", "holds_amount"),
+			"Total amount of the active holds.",
+			nil, nil,
+		),
+	}
+}
+
+// Source of the statistics of the database pool (e.g. *database/sql.DB)
+type DBStater interface {
+	Stats() std.DBStats
+}
+
+// Collector of the statistics of the database pool
+type dbStatsCollector struct {
+	db           DBStater
+	open         *prometheus.Desc
+	inUse        *prometheus.Desc
+	idle         *prometheus.Desc
+	waitCount    *prometheus.Desc
+	waitDuration *prometheus.Desc
+}
+
+func (c *dbStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.open
+	ch <- c.inUse
+	ch <- c.idle
+	ch <- c.waitCount
+	ch <- c.waitDuration
+}
+
+func (c *dbStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.db.Stats()
+	ch <- prometheus.MustNewConstMetric(c.open, prometheus.GaugeValue, float64(stats.OpenConnections))
+	ch <- prometheus.MustNewConstMetric(c.inUse, prometheus.GaugeValue, float64(stats.InUse))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(stats.Idle))
+	ch <- prometheus.MustNewConstMetric(c.waitCount, prometheus.CounterValue, float64(stats.WaitCount))
+	ch <- prometheus.MustNewConstMetric(c.waitDuration, prometheus.CounterValue, stats.WaitDuration.Seconds())
+}
+
+func NewDBStatsCollector(db DBStater) prometheus.Collector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, "db", name),
+			help,
+			nil, nil,
+		)
+	}
+
+	return &dbStatsCollector{
+		db:           db,
+		open:         desc("open_connections", "Count of the established connections."),
+		inUse:        desc("in_use_connections", "Count of the connections in use."),
+		idle:         desc("idle_connections", "Count of the idle connections."),
+		waitCount:    desc("wait_count_total", "Count of the waits for a connection."),
+		waitDuration: desc("wait_duration_seconds_total", "Total time blocked waiting for a connection."),
+	}
+}
+
+// Collector of the messages, that are received by the transport, but are not handled yet
+type pendingCollector struct {
+	transport PendingCounter
+	desc      *prometheus.Desc
+}
+
+func (c *pendingCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *pendingCollector) Collect(ch chan<- prometheus.Metric) {
+	pending, err := c.transport.Pending()
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.desc, err)
+		return
+	}
+
+	for subject, count := range pending {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(count), subject)
+	}
+}
+
+func NewPendingCollector(transport PendingCounter) prometheus.Collector {
+	return &pendingCollector{
+		transport: transport,
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, "broker", "pending_messages"),
+			"Count of the received messages, that are not handled yet.",
+			[]string{"subject"}, nil,
+		),
+	}
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"billing/domain"
+	"context"
+	"errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestMetricsManager(t *testing.T) {
+	metrics, err := NewMetrics(prometheus.NewRegistry())
+	require.NoError(t, err)
+
+	mock := &managerMock{}
+	manager := NewMetricsManager(mock, metrics)
+	ctx := context.Background()
+
+	require.NoError(t, manager.Credit(ctx, 1, 1, 10))
+	require.NoError(t, manager.Credit(ctx, 2, 1, 10))
+	mock.err = domain.ErrNoMoney
+	assert.Equal(t, domain.ErrNoMoney, manager.Transfer(ctx, 3, 1, 2, 10))
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(metrics.operations.WithLabelValues("credit", "ok")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.operations.WithLabelValues("transfer", domain.ErrorCodeNoMoney)))
+	assert.Equal(t, []string{"credit 1 1 10", "credit 2 1 10", "transfer 3 1 2 10"}, mock.calls)
+}
+
+type holdsMock struct {
+	count  int64
+	amount float32
+	err    error
+}
+
+func (m *holdsMock) Summary(ctx context.Context) (int64, float32, error) {
+	return m.count, m.amount, m.err
+}
+
+func TestHoldsCollector(t *testing.T) {
+	holds := &holdsMock{count: 3, amount: 42.5}
+	collector := NewHoldsCollector(holds).(*holdsCollector)
+
+	ch := make(chan prometheus.Metric, 2)
+	collector.Collect(ch)
+	close(ch)
+	require.Len(t, ch, 2)
+
+	holds.err = errors.New("connection refused")
+	ch = make(chan prometheus.Metric, 2)
+	collector.Collect(ch)
+	close(ch)
+	require.Len(t, ch, 2)
+}
+
+type pendingMock map[string]int
+
+func (m pendingMock) Pending() (map[string]int, error) {
+	return m, nil
+}
+
+func TestPendingCollector(t *testing.T) {
+	collector := NewPendingCollector(pendingMock{"bank.credit": 5})
+	assert.Equal(t, float64(5), testutil.ToFloat64(collector))
+}
@@ -0,0 +1,325 @@
+package service
+
+import (
+	"billing/domain"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/nats-io/nats.go"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+type natsMessage struct {
+	conn *nats.Conn
+	msg  *nats.Msg
+}
+
+func (m *natsMessage) Subject() string {
+	return m.msg.Subject
+}
+
+func (m *natsMessage) Data() []byte {
+	return m.msg.Data
+}
+
+func (m *natsMessage) Reply(data []byte) error {
+	return m.ReplyWithHeaders(data, nil)
+}
+
+// Get headers of the message. Keys are lower cased, only the first value of the key is used.
+func (m *natsMessage) Headers() map[string]string {
+	headers := make(map[string]string, len(m.msg.Header))
+	for key, values := range m.msg.Header {
+		if len(values) != 0 {
+			headers[strings.ToLower(key)] = values[0]
+		}
+	}
+	return headers
+}
+
+func (m *natsMessage) ReplyWithHeaders(data []byte, headers map[string]string) error {
+	if m.msg.Reply == "" {
+		return nil
+	}
+	return publishNats(m.conn, m.msg.Reply, data, headers)
+}
+
+// Interval of polling of the draining subscriptions
+const natsDrainInterval = 10 * time.Millisecond
+
+type natsTransport struct {
+	conn    *nats.Conn
+	options domain.NatsOptions
+	mu      sync.Mutex
+	subs    []*nats.Subscription
+}
+
+func (t *natsTransport) Subscribe(
+	subject, queue string,
+	handler Handler,
+) error {
+	sub, err := t.conn.QueueSubscribe(
+		subject,
+		queue,
+		func(msg *nats.Msg) {
+			handler(&natsMessage{conn: t.conn, msg: msg})
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	// Messages above the limits are dropped and reported as slow consumer errors
+	if t.options.PendingMessages > 0 || t.options.PendingBytes > 0 {
+		msgs, bytes, err := sub.PendingLimits()
+		if err != nil {
+			return err
+		}
+		if t.options.PendingMessages > 0 {
+			msgs = t.options.PendingMessages
+		}
+		if t.options.PendingBytes > 0 {
+			bytes = t.options.PendingBytes
+		}
+		err = sub.SetPendingLimits(msgs, bytes)
+		if err != nil {
+			return err
+		}
+	}
+
+	t.mu.Lock()
+	t.subs = append(t.subs, sub)
+	t.mu.Unlock()
+	return nil
+}
+
+// Drain subscriptions instead of the whole connection,
+// because the connection is required for replies.
+// Subscription becomes invalid, when all pending messages are handled.
+func (t *natsTransport) Drain() error {
+	t.mu.Lock()
+	subs := t.subs
+	t.subs = nil
+	t.mu.Unlock()
+
+	for _, sub := range subs {
+		err := sub.Drain()
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, sub := range subs {
+		for sub.IsValid() {
+			time.Sleep(natsDrainInterval)
+		}
+	}
+
+	return nil
+}
+
+func (t *natsTransport) Pending() (map[string]int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	pending := make(map[string]int, len(t.subs))
+	for _, sub := range t.subs {
+		msgs, _, err := sub.Pending()
+		if err != nil {
+			return nil, err
+		}
+		pending[sub.Subject] += msgs
+	}
+
+	return pending, nil
+}
+
+func (t *natsTransport) Publish(subject string, data []byte) error {
+	return t.conn.Publish(subject, data)
+}
+
+func (t *natsTransport) PublishWithHeaders(subject string, data []byte, headers map[string]string) error {
+	return publishNats(t.conn, subject, data, headers)
+}
+
+func (t *natsTransport) Check(ctx context.Context) error {
+	if t.conn.IsConnected() {
+		return nil
+	}
+	if err := t.conn.LastError(); err != nil {
+		return fmt.Errorf("NATS is disconnected: %v", err)
+	}
+	return errors.New("NATS is disconnected")
+}
+
+func (t *natsTransport) Close() error {
+	err := t.conn.Flush()
+	t.conn.Close()
+	return err
+}
+
+// Publish message with headers.
+// Headers are omitted, if the server does not support them (before NATS 2.2).
+func publishNats(
+	conn *nats.Conn,
+	subject string,
+	data []byte,
+	headers map[string]string,
+) error {
+	if len(headers) == 0 || !conn.HeadersSupported() {
+		return conn.Publish(subject, data)
+	}
+
+	msg := nats.NewMsg(subject)
+	msg.Data = data
+	for key, value := range headers {
+		msg.Header.Set(key, value)
+	}
+	return conn.PublishMsg(msg)
+}
+
+// Create transport over the NATS connection
+func NewNatsTransport(
+	conn *nats.Conn,
+	options domain.NatsOptions,
+) Transport {
+	return &natsTransport{
+		conn:    conn,
+		options: options,
+	}
+}
+
+// Connect to the NATS cluster.
+// Connection is retried on startup, so the service can be started before the broker.
+func ConnectNats(
+	ctx context.Context,
+	servers []string,
+	options domain.NatsOptions,
+	logger *slog.Logger,
+) (*nats.Conn, error) {
+	opts, err := natsOptions(options, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	url := strings.Join(servers, ",")
+	if url == "" {
+		url = nats.DefaultURL
+	}
+
+	var conn *nats.Conn
+	err = retry(
+		ctx,
+		options.ConnectAttempts,
+		time.Duration(options.ConnectWait)*time.Second,
+		logger,
+		func() error {
+			var err error
+			conn, err = nats.Connect(url, opts...)
+			return err
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("NATS is connected", "url", conn.ConnectedUrlRedacted())
+	return conn, nil
+}
+
+func natsOptions(
+	options domain.NatsOptions,
+	logger *slog.Logger,
+) ([]nats.Option, error) {
+	opts := []nats.Option{
+		nats.Name(options.Name),
+		nats.Timeout(time.Duration(options.ConnectTimeout) * time.Second),
+		nats.MaxReconnects(options.MaxReconnects),
+		nats.ReconnectWait(time.Duration(options.ReconnectWait) * time.Second),
+		nats.DisconnectHandler(func(nc *nats.Conn) {
+			if err := nc.LastError(); err != nil {
+				logger.Warn("NATS is disconnected", "error", err)
+				return
+			}
+			logger.Warn("NATS is disconnected")
+		}),
+		nats.ReconnectHandler(func(nc *nats.Conn) {
+			logger.Info("NATS is reconnected", "url", nc.ConnectedUrlRedacted())
+		}),
+		nats.ClosedHandler(func(nc *nats.Conn) {
+			logger.Info("NATS connection is closed")
+		}),
+		nats.DiscoveredServersHandler(func(nc *nats.Conn) {
+			logger.Info("NATS servers are discovered", "servers", nc.DiscoveredServers())
+		}),
+		nats.ErrorHandler(func(nc *nats.Conn, sub *nats.Subscription, err error) {
+			if sub != nil {
+				logger.Error("NATS subscription failed", "subject", sub.Subject, "error", err)
+				return
+			}
+			logger.Error("NATS connection failed", "error", err)
+		}),
+	}
+
+	if options.Credentials != "" {
+		opts = append(opts, nats.UserCredentials(options.Credentials))
+	}
+
+	if options.NKeySeed != "" {
+		opt, err := nats.NkeyOptionFromSeed(options.NKeySeed)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, opt)
+	}
+
+	if options.User != "" {
+		opts = append(opts, nats.UserInfo(options.User, options.Password))
+	}
+
+	if options.Token != "" {
+		opts = append(opts, nats.Token(options.Token))
+	}
+
+	if options.TlsCa != "" {
+		opts = append(opts, nats.RootCAs(options.TlsCa))
+	}
+
+	if options.TlsCert != "" || options.TlsKey != "" {
+		opts = append(opts, nats.ClientCert(options.TlsCert, options.TlsKey))
+	}
+
+	return opts, nil
+}
+
+// Call action until success.
+// Zero or negative count of attempts means infinite retries.
+func retry(
+	ctx context.Context,
+	attempts int,
+	wait time.Duration,
+	logger *slog.Logger,
+	action func() error,
+) error {
+	for attempt := 1; ; attempt++ {
+		err := action()
+		if err == nil {
+			return nil
+		}
+
+		if attempts > 0 && attempt >= attempts {
+			return err
+		}
+
+		logger.Warn("Attempt is failed", "attempt", attempt, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
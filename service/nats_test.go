@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) {
+	type Src struct {
+		attempts int
+		failures int
+	}
+
+	type Dst struct {
+		calls int
+		err   bool
+	}
+
+	type Test struct {
+		src Src
+		dst Dst
+	}
+
+	tests := map[string]Test{
+		"Action must be retried until success": {
+			src: Src{
+				attempts: 0,
+				failures: 3,
+			},
+			dst: Dst{
+				calls: 4,
+			},
+		},
+		"Last error must be returned when attempts are exhausted": {
+			src: Src{
+				attempts: 2,
+				failures: 3,
+			},
+			dst: Dst{
+				calls: 2,
+				err:   true,
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			calls := 0
+			err := retry(
+				context.Background(),
+				test.src.attempts,
+				time.Millisecond,
+				slog.New(slog.DiscardHandler),
+				func() error {
+					calls++
+					if calls <= test.src.failures {
+						return errors.New("no servers available")
+					}
+					return nil
+				},
+			)
+			assert.Equal(t, test.dst.err, err != nil)
+			assert.Equal(t, test.dst.calls, calls)
+		})
+	}
+}
+
+func TestRetry_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := retry(ctx, 0, time.Hour, slog.New(slog.DiscardHandler), func() error {
+		return errors.New("no servers available")
+	})
+	assert.Equal(t, context.Canceled, err)
+}
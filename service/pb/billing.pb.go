@@ -0,0 +1,1149 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: billing.proto
+
+package pb
+
+import (
+	context "context"
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+type Operation int32
+
+const (
+	Operation_OPERATION_UNKNOWN         Operation = 0
+	Operation_OPERATION_CREDIT          Operation = 1
+	Operation_OPERATION_DEBIT           Operation = 2
+	Operation_OPERATION_TRANSFER_SRC    Operation = 3
+	Operation_OPERATION_TRANSFER_DST    Operation = 4
+	Operation_OPERATION_ACQUIRE         Operation = 5
+	Operation_OPERATION_COMMIT          Operation = 6
+	Operation_OPERATION_ROLLBACK        Operation = 7
+	Operation_OPERATION_TRANSFER_REFUND Operation = 8
+	Operation_OPERATION_COMMIT_DST      Operation = 9
+	Operation_OPERATION_ESCROW_OPEN     Operation = 10
+	Operation_OPERATION_ESCROW_RELEASE  Operation = 11
+	Operation_OPERATION_ESCROW_REFUND   Operation = 12
+	Operation_OPERATION_REVERSE         Operation = 13
+	Operation_OPERATION_REVERSE_DST     Operation = 14
+)
+
+var Operation_name = map[int32]string{
+	0:  "OPERATION_UNKNOWN",
+	1:  "OPERATION_CREDIT",
+	2:  "OPERATION_DEBIT",
+	3:  "OPERATION_TRANSFER_SRC",
+	4:  "OPERATION_TRANSFER_DST",
+	5:  "OPERATION_ACQUIRE",
+	6:  "OPERATION_COMMIT",
+	7:  "OPERATION_ROLLBACK",
+	8:  "OPERATION_TRANSFER_REFUND",
+	9:  "OPERATION_COMMIT_DST",
+	10: "OPERATION_ESCROW_OPEN",
+	11: "OPERATION_ESCROW_RELEASE",
+	12: "OPERATION_ESCROW_REFUND",
+	13: "OPERATION_REVERSE",
+	14: "OPERATION_REVERSE_DST",
+}
+
+var Operation_value = map[string]int32{
+	"OPERATION_UNKNOWN":         0,
+	"OPERATION_CREDIT":          1,
+	"OPERATION_DEBIT":           2,
+	"OPERATION_TRANSFER_SRC":    3,
+	"OPERATION_TRANSFER_DST":    4,
+	"OPERATION_ACQUIRE":         5,
+	"OPERATION_COMMIT":          6,
+	"OPERATION_ROLLBACK":        7,
+	"OPERATION_TRANSFER_REFUND": 8,
+	"OPERATION_COMMIT_DST":      9,
+	"OPERATION_ESCROW_OPEN":     10,
+	"OPERATION_ESCROW_RELEASE":  11,
+	"OPERATION_ESCROW_REFUND":   12,
+	"OPERATION_REVERSE":         13,
+	"OPERATION_REVERSE_DST":     14,
+}
+
+func (x Operation) String() string {
+	return proto.EnumName(Operation_name, int32(x))
+}
+
+func (Operation) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_958db8ba491a6b57, []int{0}
+}
+
+type CreditRequest struct {
+	Uid                  int64    `protobuf:"varint,1,opt,name=uid,proto3" json:"uid,omitempty"`
+	Account              uint32   `protobuf:"varint,2,opt,name=account,proto3" json:"account,omitempty"`
+	Amount               float32  `protobuf:"fixed32,3,opt,name=amount,proto3" json:"amount,omitempty"`
+	CorrelationId        string   `protobuf:"bytes,4,opt,name=correlation_id,json=correlationId,proto3" json:"correlation_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CreditRequest) Reset()         { *m = CreditRequest{} }
+func (m *CreditRequest) String() string { return proto.CompactTextString(m) }
+func (*CreditRequest) ProtoMessage()    {}
+func (*CreditRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_958db8ba491a6b57, []int{0}
+}
+
+func (m *CreditRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CreditRequest.Unmarshal(m, b)
+}
+func (m *CreditRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CreditRequest.Marshal(b, m, deterministic)
+}
+func (m *CreditRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CreditRequest.Merge(m, src)
+}
+func (m *CreditRequest) XXX_Size() int {
+	return xxx_messageInfo_CreditRequest.Size(m)
+}
+func (m *CreditRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CreditRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CreditRequest proto.InternalMessageInfo
+
+func (m *CreditRequest) GetUid() int64 {
+	if m != nil {
+		return m.Uid
+	}
+	return 0
+}
+
+func (m *CreditRequest) GetAccount() uint32 {
+	if m != nil {
+		return m.Account
+	}
+	return 0
+}
+
+func (m *CreditRequest) GetAmount() float32 {
+	if m != nil {
+		return m.Amount
+	}
+	return 0
+}
+
+func (m *CreditRequest) GetCorrelationId() string {
+	if m != nil {
+		return m.CorrelationId
+	}
+	return ""
+}
+
+type DebitRequest struct {
+	Uid                  int64    `protobuf:"varint,1,opt,name=uid,proto3" json:"uid,omitempty"`
+	Account              uint32   `protobuf:"varint,2,opt,name=account,proto3" json:"account,omitempty"`
+	Amount               float32  `protobuf:"fixed32,3,opt,name=amount,proto3" json:"amount,omitempty"`
+	CorrelationId        string   `protobuf:"bytes,4,opt,name=correlation_id,json=correlationId,proto3" json:"correlation_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DebitRequest) Reset()         { *m = DebitRequest{} }
+func (m *DebitRequest) String() string { return proto.CompactTextString(m) }
+func (*DebitRequest) ProtoMessage()    {}
+func (*DebitRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_958db8ba491a6b57, []int{1}
+}
+
+func (m *DebitRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DebitRequest.Unmarshal(m, b)
+}
+func (m *DebitRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DebitRequest.Marshal(b, m, deterministic)
+}
+func (m *DebitRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DebitRequest.Merge(m, src)
+}
+func (m *DebitRequest) XXX_Size() int {
+	return xxx_messageInfo_DebitRequest.Size(m)
+}
+func (m *DebitRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DebitRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DebitRequest proto.InternalMessageInfo
+
+func (m *DebitRequest) GetUid() int64 {
+	if m != nil {
+		return m.Uid
+	}
+	return 0
+}
+
+func (m *DebitRequest) GetAccount() uint32 {
+	if m != nil {
+		return m.Account
+	}
+	return 0
+}
+
+func (m *DebitRequest) GetAmount() float32 {
+	if m != nil {
+		return m.Amount
+	}
+	return 0
+}
+
+func (m *DebitRequest) GetCorrelationId() string {
+	if m != nil {
+		return m.CorrelationId
+	}
+	return ""
+}
+
+type TransferRequest struct {
+	Uid                  int64    `protobuf:"varint,1,opt,name=uid,proto3" json:"uid,omitempty"`
+	Src                  uint32   `protobuf:"varint,2,opt,name=src,proto3" json:"src,omitempty"`
+	Dst                  uint32   `protobuf:"varint,3,opt,name=dst,proto3" json:"dst,omitempty"`
+	Amount               float32  `protobuf:"fixed32,4,opt,name=amount,proto3" json:"amount,omitempty"`
+	CorrelationId        string   `protobuf:"bytes,5,opt,name=correlation_id,json=correlationId,proto3" json:"correlation_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *TransferRequest) Reset()         { *m = TransferRequest{} }
+func (m *TransferRequest) String() string { return proto.CompactTextString(m) }
+func (*TransferRequest) ProtoMessage()    {}
+func (*TransferRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_958db8ba491a6b57, []int{2}
+}
+
+func (m *TransferRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TransferRequest.Unmarshal(m, b)
+}
+func (m *TransferRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TransferRequest.Marshal(b, m, deterministic)
+}
+func (m *TransferRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TransferRequest.Merge(m, src)
+}
+func (m *TransferRequest) XXX_Size() int {
+	return xxx_messageInfo_TransferRequest.Size(m)
+}
+func (m *TransferRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_TransferRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TransferRequest proto.InternalMessageInfo
+
+func (m *TransferRequest) GetUid() int64 {
+	if m != nil {
+		return m.Uid
+	}
+	return 0
+}
+
+func (m *TransferRequest) GetSrc() uint32 {
+	if m != nil {
+		return m.Src
+	}
+	return 0
+}
+
+func (m *TransferRequest) GetDst() uint32 {
+	if m != nil {
+		return m.Dst
+	}
+	return 0
+}
+
+func (m *TransferRequest) GetAmount() float32 {
+	if m != nil {
+		return m.Amount
+	}
+	return 0
+}
+
+func (m *TransferRequest) GetCorrelationId() string {
+	if m != nil {
+		return m.CorrelationId
+	}
+	return ""
+}
+
+type AcquireRequest struct {
+	Uid                  int64    `protobuf:"varint,1,opt,name=uid,proto3" json:"uid,omitempty"`
+	Account              uint32   `protobuf:"varint,2,opt,name=account,proto3" json:"account,omitempty"`
+	Amount               float32  `protobuf:"fixed32,3,opt,name=amount,proto3" json:"amount,omitempty"`
+	CorrelationId        string   `protobuf:"bytes,4,opt,name=correlation_id,json=correlationId,proto3" json:"correlation_id,omitempty"`
+	Dst                  uint32   `protobuf:"varint,5,opt,name=dst,proto3" json:"dst,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AcquireRequest) Reset()         { *m = AcquireRequest{} }
+func (m *AcquireRequest) String() string { return proto.CompactTextString(m) }
+func (*AcquireRequest) ProtoMessage()    {}
+func (*AcquireRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_958db8ba491a6b57, []int{3}
+}
+
+func (m *AcquireRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AcquireRequest.Unmarshal(m, b)
+}
+func (m *AcquireRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AcquireRequest.Marshal(b, m, deterministic)
+}
+func (m *AcquireRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AcquireRequest.Merge(m, src)
+}
+func (m *AcquireRequest) XXX_Size() int {
+	return xxx_messageInfo_AcquireRequest.Size(m)
+}
+func (m *AcquireRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_AcquireRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AcquireRequest proto.InternalMessageInfo
+
+func (m *AcquireRequest) GetUid() int64 {
+	if m != nil {
+		return m.Uid
+	}
+	return 0
+}
+
+func (m *AcquireRequest) GetAccount() uint32 {
+	if m != nil {
+		return m.Account
+	}
+	return 0
+}
+
+func (m *AcquireRequest) GetAmount() float32 {
+	if m != nil {
+		return m.Amount
+	}
+	return 0
+}
+
+func (m *AcquireRequest) GetCorrelationId() string {
+	if m != nil {
+		return m.CorrelationId
+	}
+	return ""
+}
+
+func (m *AcquireRequest) GetDst() uint32 {
+	if m != nil {
+		return m.Dst
+	}
+	return 0
+}
+
+type CommitRequest struct {
+	Uid                  int64    `protobuf:"varint,1,opt,name=uid,proto3" json:"uid,omitempty"`
+	Account              uint32   `protobuf:"varint,2,opt,name=account,proto3" json:"account,omitempty"`
+	CorrelationId        string   `protobuf:"bytes,3,opt,name=correlation_id,json=correlationId,proto3" json:"correlation_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CommitRequest) Reset()         { *m = CommitRequest{} }
+func (m *CommitRequest) String() string { return proto.CompactTextString(m) }
+func (*CommitRequest) ProtoMessage()    {}
+func (*CommitRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_958db8ba491a6b57, []int{4}
+}
+
+func (m *CommitRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CommitRequest.Unmarshal(m, b)
+}
+func (m *CommitRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CommitRequest.Marshal(b, m, deterministic)
+}
+func (m *CommitRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CommitRequest.Merge(m, src)
+}
+func (m *CommitRequest) XXX_Size() int {
+	return xxx_messageInfo_CommitRequest.Size(m)
+}
+func (m *CommitRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CommitRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CommitRequest proto.InternalMessageInfo
+
+func (m *CommitRequest) GetUid() int64 {
+	if m != nil {
+		return m.Uid
+	}
+	return 0
+}
+
+func (m *CommitRequest) GetAccount() uint32 {
+	if m != nil {
+		return m.Account
+	}
+	return 0
+}
+
+func (m *CommitRequest) GetCorrelationId() string {
+	if m != nil {
+		return m.CorrelationId
+	}
+	return ""
+}
+
+type RollbackRequest struct {
+	Uid                  int64    `protobuf:"varint,1,opt,name=uid,proto3" json:"uid,omitempty"`
+	Account              uint32   `protobuf:"varint,2,opt,name=account,proto3" json:"account,omitempty"`
+	CorrelationId        string   `protobuf:"bytes,3,opt,name=correlation_id,json=correlationId,proto3" json:"correlation_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RollbackRequest) Reset()         { *m = RollbackRequest{} }
+func (m *RollbackRequest) String() string { return proto.CompactTextString(m) }
+func (*RollbackRequest) ProtoMessage()    {}
+func (*RollbackRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_958db8ba491a6b57, []int{5}
+}
+
+func (m *RollbackRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RollbackRequest.Unmarshal(m, b)
+}
+func (m *RollbackRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RollbackRequest.Marshal(b, m, deterministic)
+}
+func (m *RollbackRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RollbackRequest.Merge(m, src)
+}
+func (m *RollbackRequest) XXX_Size() int {
+	return xxx_messageInfo_RollbackRequest.Size(m)
+}
+func (m *RollbackRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RollbackRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RollbackRequest proto.InternalMessageInfo
+
+func (m *RollbackRequest) GetUid() int64 {
+	if m != nil {
+		return m.Uid
+	}
+	return 0
+}
+
+func (m *RollbackRequest) GetAccount() uint32 {
+	if m != nil {
+		return m.Account
+	}
+	return 0
+}
+
+func (m *RollbackRequest) GetCorrelationId() string {
+	if m != nil {
+		return m.CorrelationId
+	}
+	return ""
+}
+
+type OperationResponse struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *OperationResponse) Reset()         { *m = OperationResponse{} }
+func (m *OperationResponse) String() string { return proto.CompactTextString(m) }
+func (*OperationResponse) ProtoMessage()    {}
+func (*OperationResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_958db8ba491a6b57, []int{6}
+}
+
+func (m *OperationResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_OperationResponse.Unmarshal(m, b)
+}
+func (m *OperationResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_OperationResponse.Marshal(b, m, deterministic)
+}
+func (m *OperationResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_OperationResponse.Merge(m, src)
+}
+func (m *OperationResponse) XXX_Size() int {
+	return xxx_messageInfo_OperationResponse.Size(m)
+}
+func (m *OperationResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_OperationResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_OperationResponse proto.InternalMessageInfo
+
+type BalanceRequest struct {
+	Account              uint32   `protobuf:"varint,1,opt,name=account,proto3" json:"account,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *BalanceRequest) Reset()         { *m = BalanceRequest{} }
+func (m *BalanceRequest) String() string { return proto.CompactTextString(m) }
+func (*BalanceRequest) ProtoMessage()    {}
+func (*BalanceRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_958db8ba491a6b57, []int{7}
+}
+
+func (m *BalanceRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BalanceRequest.Unmarshal(m, b)
+}
+func (m *BalanceRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BalanceRequest.Marshal(b, m, deterministic)
+}
+func (m *BalanceRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BalanceRequest.Merge(m, src)
+}
+func (m *BalanceRequest) XXX_Size() int {
+	return xxx_messageInfo_BalanceRequest.Size(m)
+}
+func (m *BalanceRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_BalanceRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BalanceRequest proto.InternalMessageInfo
+
+func (m *BalanceRequest) GetAccount() uint32 {
+	if m != nil {
+		return m.Account
+	}
+	return 0
+}
+
+type BalanceResponse struct {
+	Amount               float32  `protobuf:"fixed32,1,opt,name=amount,proto3" json:"amount,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *BalanceResponse) Reset()         { *m = BalanceResponse{} }
+func (m *BalanceResponse) String() string { return proto.CompactTextString(m) }
+func (*BalanceResponse) ProtoMessage()    {}
+func (*BalanceResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_958db8ba491a6b57, []int{8}
+}
+
+func (m *BalanceResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BalanceResponse.Unmarshal(m, b)
+}
+func (m *BalanceResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BalanceResponse.Marshal(b, m, deterministic)
+}
+func (m *BalanceResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BalanceResponse.Merge(m, src)
+}
+func (m *BalanceResponse) XXX_Size() int {
+	return xxx_messageInfo_BalanceResponse.Size(m)
+}
+func (m *BalanceResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_BalanceResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BalanceResponse proto.InternalMessageInfo
+
+func (m *BalanceResponse) GetAmount() float32 {
+	if m != nil {
+		return m.Amount
+	}
+	return 0
+}
+
+type HistoryRequest struct {
+	Account              uint32   `protobuf:"varint,1,opt,name=account,proto3" json:"account,omitempty"`
+	Offset               uint32   `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"`
+	Limit                uint32   `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *HistoryRequest) Reset()         { *m = HistoryRequest{} }
+func (m *HistoryRequest) String() string { return proto.CompactTextString(m) }
+func (*HistoryRequest) ProtoMessage()    {}
+func (*HistoryRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_958db8ba491a6b57, []int{9}
+}
+
+func (m *HistoryRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_HistoryRequest.Unmarshal(m, b)
+}
+func (m *HistoryRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_HistoryRequest.Marshal(b, m, deterministic)
+}
+func (m *HistoryRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_HistoryRequest.Merge(m, src)
+}
+func (m *HistoryRequest) XXX_Size() int {
+	return xxx_messageInfo_HistoryRequest.Size(m)
+}
+func (m *HistoryRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_HistoryRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_HistoryRequest proto.InternalMessageInfo
+
+func (m *HistoryRequest) GetAccount() uint32 {
+	if m != nil {
+		return m.Account
+	}
+	return 0
+}
+
+func (m *HistoryRequest) GetOffset() uint32 {
+	if m != nil {
+		return m.Offset
+	}
+	return 0
+}
+
+func (m *HistoryRequest) GetLimit() uint32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+type HistoryRecord struct {
+	Id                   int64     `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Uid                  int64     `protobuf:"varint,2,opt,name=uid,proto3" json:"uid,omitempty"`
+	Account              uint32    `protobuf:"varint,3,opt,name=account,proto3" json:"account,omitempty"`
+	Amount               float32   `protobuf:"fixed32,4,opt,name=amount,proto3" json:"amount,omitempty"`
+	Op                   Operation `protobuf:"varint,5,opt,name=op,proto3,enum=billing.Operation" json:"op,omitempty"`
+	Registered           int64     `protobuf:"varint,6,opt,name=registered,proto3" json:"registered,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
+}
+
+func (m *HistoryRecord) Reset()         { *m = HistoryRecord{} }
+func (m *HistoryRecord) String() string { return proto.CompactTextString(m) }
+func (*HistoryRecord) ProtoMessage()    {}
+func (*HistoryRecord) Descriptor() ([]byte, []int) {
+	return fileDescriptor_958db8ba491a6b57, []int{10}
+}
+
+func (m *HistoryRecord) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_HistoryRecord.Unmarshal(m, b)
+}
+func (m *HistoryRecord) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_HistoryRecord.Marshal(b, m, deterministic)
+}
+func (m *HistoryRecord) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_HistoryRecord.Merge(m, src)
+}
+func (m *HistoryRecord) XXX_Size() int {
+	return xxx_messageInfo_HistoryRecord.Size(m)
+}
+func (m *HistoryRecord) XXX_DiscardUnknown() {
+	xxx_messageInfo_HistoryRecord.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_HistoryRecord proto.InternalMessageInfo
+
+func (m *HistoryRecord) GetId() int64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *HistoryRecord) GetUid() int64 {
+	if m != nil {
+		return m.Uid
+	}
+	return 0
+}
+
+func (m *HistoryRecord) GetAccount() uint32 {
+	if m != nil {
+		return m.Account
+	}
+	return 0
+}
+
+func (m *HistoryRecord) GetAmount() float32 {
+	if m != nil {
+		return m.Amount
+	}
+	return 0
+}
+
+func (m *HistoryRecord) GetOp() Operation {
+	if m != nil {
+		return m.Op
+	}
+	return Operation_OPERATION_UNKNOWN
+}
+
+func (m *HistoryRecord) GetRegistered() int64 {
+	if m != nil {
+		return m.Registered
+	}
+	return 0
+}
+
+type HistoryResponse struct {
+	Records              []*HistoryRecord `protobuf:"bytes,1,rep,name=records,proto3" json:"records,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *HistoryResponse) Reset()         { *m = HistoryResponse{} }
+func (m *HistoryResponse) String() string { return proto.CompactTextString(m) }
+func (*HistoryResponse) ProtoMessage()    {}
+func (*HistoryResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_958db8ba491a6b57, []int{11}
+}
+
+func (m *HistoryResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_HistoryResponse.Unmarshal(m, b)
+}
+func (m *HistoryResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_HistoryResponse.Marshal(b, m, deterministic)
+}
+func (m *HistoryResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_HistoryResponse.Merge(m, src)
+}
+func (m *HistoryResponse) XXX_Size() int {
+	return xxx_messageInfo_HistoryResponse.Size(m)
+}
+func (m *HistoryResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_HistoryResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_HistoryResponse proto.InternalMessageInfo
+
+func (m *HistoryResponse) GetRecords() []*HistoryRecord {
+	if m != nil {
+		return m.Records
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterEnum("billing.Operation", Operation_name, Operation_value)
+	proto.RegisterType((*CreditRequest)(nil), "billing.CreditRequest")
+	proto.RegisterType((*DebitRequest)(nil), "billing.DebitRequest")
+	proto.RegisterType((*TransferRequest)(nil), "billing.TransferRequest")
+	proto.RegisterType((*AcquireRequest)(nil), "billing.AcquireRequest")
+	proto.RegisterType((*CommitRequest)(nil), "billing.CommitRequest")
+	proto.RegisterType((*RollbackRequest)(nil), "billing.RollbackRequest")
+	proto.RegisterType((*OperationResponse)(nil), "billing.OperationResponse")
+	proto.RegisterType((*BalanceRequest)(nil), "billing.BalanceRequest")
+	proto.RegisterType((*BalanceResponse)(nil), "billing.BalanceResponse")
+	proto.RegisterType((*HistoryRequest)(nil), "billing.HistoryRequest")
+	proto.RegisterType((*HistoryRecord)(nil), "billing.HistoryRecord")
+	proto.RegisterType((*HistoryResponse)(nil), "billing.HistoryResponse")
+}
+
+func init() { proto.RegisterFile("billing.proto", fileDescriptor_958db8ba491a6b57) }
+
+var fileDescriptor_958db8ba491a6b57 = []byte{
+	// 726 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xc4, 0x56, 0xdd, 0x6f, 0xd2, 0x5e,
+	0x18, 0xfe, 0xb5, 0xe5, 0x63, 0x7b, 0xb7, 0xc2, 0xd9, 0xd9, 0x60, 0x1d, 0x3f, 0x35, 0xa4, 0x89,
+	0x09, 0xee, 0x62, 0x33, 0xf3, 0x4e, 0x8d, 0x11, 0xca, 0x59, 0x24, 0xdb, 0xe8, 0x3c, 0x30, 0x67,
+	0xbc, 0x21, 0xa5, 0x3d, 0x5b, 0x9a, 0x01, 0x65, 0xa7, 0xc5, 0x64, 0x5e, 0x79, 0xe5, 0x8d, 0xf1,
+	0xef, 0xf0, 0xdf, 0x34, 0x94, 0x7e, 0x02, 0x1b, 0x89, 0x89, 0x7a, 0xd7, 0xf7, 0xf3, 0x79, 0xdf,
+	0xa7, 0x2f, 0x4f, 0x01, 0xb9, 0x6f, 0x0f, 0x06, 0xf6, 0xe8, 0xfa, 0x60, 0xcc, 0x1d, 0xcf, 0xc1,
+	0xf9, 0xc0, 0x54, 0xbf, 0x80, 0xac, 0x71, 0x66, 0xd9, 0x1e, 0x65, 0xb7, 0x13, 0xe6, 0x7a, 0x18,
+	0x81, 0x34, 0xb1, 0x2d, 0x45, 0xa8, 0x0a, 0x35, 0x89, 0x4e, 0x1f, 0xb1, 0x02, 0x79, 0xc3, 0x34,
+	0x9d, 0xc9, 0xc8, 0x53, 0xc4, 0xaa, 0x50, 0x93, 0x69, 0x68, 0xe2, 0x32, 0xe4, 0x8c, 0xa1, 0x1f,
+	0x90, 0xaa, 0x42, 0x4d, 0xa4, 0x81, 0x85, 0x9f, 0x42, 0xc1, 0x74, 0x38, 0x67, 0x03, 0xc3, 0xb3,
+	0x9d, 0x51, 0xcf, 0xb6, 0x94, 0x4c, 0x55, 0xa8, 0xad, 0x53, 0x39, 0xe1, 0x6d, 0x59, 0xea, 0x1d,
+	0x6c, 0x36, 0x59, 0xff, 0x9f, 0x40, 0x7f, 0x13, 0xa0, 0xd8, 0xe5, 0xc6, 0xc8, 0xbd, 0x62, 0xfc,
+	0x7e, 0x78, 0x04, 0x92, 0xcb, 0xcd, 0x00, 0x7a, 0xfa, 0x38, 0xf5, 0x58, 0xee, 0x0c, 0x53, 0xa6,
+	0xd3, 0xc7, 0xc4, 0x20, 0x99, 0x15, 0x83, 0x64, 0x97, 0x0d, 0xf2, 0x43, 0x80, 0x42, 0xdd, 0xbc,
+	0x9d, 0xd8, 0x9c, 0xfd, 0x7d, 0x1a, 0xc2, 0x75, 0xb2, 0xd1, 0x3a, 0x6a, 0x1f, 0x64, 0xcd, 0x19,
+	0x0e, 0x7f, 0xef, 0xa5, 0x2c, 0xa2, 0x4a, 0xcb, 0x76, 0xb6, 0xa0, 0x48, 0x9d, 0xc1, 0xa0, 0x6f,
+	0x98, 0x37, 0x7f, 0x10, 0x65, 0x1b, 0xb6, 0xf4, 0x31, 0xe3, 0xbe, 0x49, 0x99, 0x3b, 0x76, 0x46,
+	0x2e, 0x53, 0xf7, 0xa1, 0xd0, 0x30, 0x06, 0xc6, 0xc8, 0x8c, 0xd8, 0x4e, 0xe0, 0x08, 0x29, 0x1c,
+	0xf5, 0x19, 0x14, 0xa3, 0xdc, 0x59, 0x79, 0x82, 0x6e, 0x21, 0x49, 0xb7, 0xfa, 0x11, 0x0a, 0xef,
+	0x6c, 0xd7, 0x73, 0xf8, 0xdd, 0xca, 0xb6, 0xd3, 0x1e, 0xce, 0xd5, 0x95, 0xcb, 0xc2, 0xbd, 0x02,
+	0x0b, 0xef, 0x40, 0x76, 0x60, 0x0f, 0xed, 0xf0, 0xb8, 0x66, 0x86, 0xfa, 0x53, 0x00, 0x39, 0x6a,
+	0x6d, 0x3a, 0xdc, 0xc2, 0x05, 0x10, 0x23, 0xa6, 0x44, 0xdb, 0x0a, 0xa9, 0x13, 0x97, 0x52, 0x27,
+	0xdd, 0x77, 0x2e, 0xe9, 0x63, 0x55, 0x41, 0x74, 0xc6, 0xfe, 0x19, 0x14, 0x8e, 0xf0, 0x41, 0x28,
+	0x15, 0x31, 0x7d, 0xa2, 0x33, 0xc6, 0x4f, 0x00, 0x38, 0xbb, 0xb6, 0x5d, 0x8f, 0x71, 0x66, 0x29,
+	0x39, 0x1f, 0x2e, 0xe1, 0x51, 0x35, 0x28, 0x46, 0x83, 0x06, 0x74, 0x3d, 0x87, 0x3c, 0xf7, 0x87,
+	0x76, 0x15, 0xa1, 0x2a, 0xd5, 0x36, 0x8e, 0xca, 0x51, 0xef, 0xd4, 0x4e, 0x34, 0x4c, 0xdb, 0xff,
+	0x2e, 0xc1, 0x7a, 0x04, 0x8b, 0x4b, 0xb0, 0xa5, 0x9f, 0x13, 0x5a, 0xef, 0xb6, 0xf4, 0x76, 0xef,
+	0xa2, 0x7d, 0xd2, 0xd6, 0x2f, 0xdb, 0xe8, 0x3f, 0xbc, 0x03, 0x28, 0x76, 0x6b, 0x94, 0x34, 0x5b,
+	0x5d, 0x24, 0xe0, 0x6d, 0x28, 0xc6, 0xde, 0x26, 0x69, 0xb4, 0xba, 0x48, 0xc4, 0x15, 0x28, 0xc7,
+	0xce, 0x2e, 0xad, 0xb7, 0x3b, 0xc7, 0x84, 0xf6, 0x3a, 0x54, 0x43, 0xd2, 0x3d, 0xb1, 0x66, 0xa7,
+	0x8b, 0x32, 0x69, 0xe4, 0xba, 0xf6, 0xfe, 0xa2, 0x45, 0x09, 0xca, 0xce, 0x21, 0xeb, 0x67, 0x67,
+	0xad, 0x2e, 0xca, 0xe1, 0x32, 0xe0, 0xd8, 0x4b, 0xf5, 0xd3, 0xd3, 0x46, 0x5d, 0x3b, 0x41, 0x79,
+	0xfc, 0x18, 0xf6, 0x96, 0x00, 0x50, 0x72, 0x7c, 0xd1, 0x6e, 0xa2, 0x35, 0xac, 0xc0, 0xce, 0x7c,
+	0x33, 0x1f, 0x7d, 0x1d, 0xef, 0x41, 0x29, 0x8e, 0x90, 0x8e, 0x46, 0xf5, 0xcb, 0x9e, 0x7e, 0x4e,
+	0xda, 0x08, 0xf0, 0x23, 0x50, 0x16, 0x42, 0x94, 0x9c, 0x92, 0x7a, 0x87, 0xa0, 0x0d, 0xfc, 0x3f,
+	0xec, 0x2e, 0x89, 0xfa, 0x78, 0x9b, 0xe9, 0x9d, 0x28, 0xf9, 0x40, 0x68, 0x87, 0x20, 0x39, 0x0d,
+	0x16, 0xb8, 0xfd, 0x39, 0x0a, 0x47, 0x5f, 0x33, 0x90, 0x6b, 0x18, 0xa3, 0x1b, 0xc6, 0xf1, 0x6b,
+	0xc8, 0xcd, 0xbe, 0x13, 0x38, 0x7e, 0x87, 0xa9, 0x0f, 0x47, 0xa5, 0xb2, 0xe4, 0x6e, 0xc2, 0x43,
+	0x78, 0x09, 0x59, 0x5f, 0xe9, 0x71, 0x29, 0x4a, 0x4a, 0x2a, 0xff, 0x83, 0xb5, 0x6f, 0x61, 0x2d,
+	0x54, 0x6a, 0xac, 0x44, 0x79, 0x73, 0xe2, 0xfd, 0x60, 0x87, 0x37, 0x90, 0x0f, 0x24, 0x16, 0xef,
+	0x46, 0x69, 0x69, 0xd1, 0x7d, 0xb0, 0x7e, 0xba, 0xbb, 0xaf, 0x89, 0xc9, 0xdd, 0x93, 0x22, 0xb9,
+	0x6a, 0xfe, 0x50, 0xed, 0x12, 0xf3, 0xcf, 0x09, 0xe0, 0x0a, 0xfc, 0x7c, 0x20, 0x44, 0x89, 0xf9,
+	0xd3, 0x32, 0x56, 0x51, 0x16, 0x03, 0x71, 0x75, 0xf0, 0x63, 0x4b, 0x54, 0xa7, 0xd5, 0xaa, 0xa2,
+	0x2c, 0x06, 0x66, 0xd5, 0x8d, 0xdd, 0x4f, 0xa5, 0x20, 0x74, 0xe8, 0x32, 0xfe, 0xd9, 0x36, 0xd9,
+	0xe1, 0xb8, 0xff, 0x6a, 0xdc, 0xef, 0xe7, 0xfc, 0x3f, 0x12, 0x2f, 0x7e, 0x0d, 0x00, 0x16, 0xca,
+	0x3a, 0xe3, 0x59, 0x08, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// BankerClient is the client API for Banker service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type BankerClient interface {
+	Credit(ctx context.Context, in *CreditRequest, opts ...grpc.CallOption) (*OperationResponse, error)
+	Debit(ctx context.Context, in *DebitRequest, opts ...grpc.CallOption) (*OperationResponse, error)
+	Transfer(ctx context.Context, in *TransferRequest, opts ...grpc.CallOption) (*OperationResponse, error)
+	Acquire(ctx context.Context, in *AcquireRequest, opts ...grpc.CallOption) (*OperationResponse, error)
+	Commit(ctx context.Context, in *CommitRequest, opts ...grpc.CallOption) (*OperationResponse, error)
+	Rollback(ctx context.Context, in *RollbackRequest, opts ...grpc.CallOption) (*OperationResponse, error)
+	Balance(ctx context.Context, in *BalanceRequest, opts ...grpc.CallOption) (*BalanceResponse, error)
+	History(ctx context.Context, in *HistoryRequest, opts ...grpc.CallOption) (*HistoryResponse, error)
+}
+
+type bankerClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewBankerClient(cc *grpc.ClientConn) BankerClient {
+	return &bankerClient{cc}
+}
+
+func (c *bankerClient) Credit(ctx context.Context, in *CreditRequest, opts ...grpc.CallOption) (*OperationResponse, error) {
+	out := new(OperationResponse)
+	err := c.cc.Invoke(ctx, "/billing.Banker/Credit", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bankerClient) Debit(ctx context.Context, in *DebitRequest, opts ...grpc.CallOption) (*OperationResponse, error) {
+	out := new(OperationResponse)
+	err := c.cc.Invoke(ctx, "/billing.Banker/Debit", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bankerClient) Transfer(ctx context.Context, in *TransferRequest, opts ...grpc.CallOption) (*OperationResponse, error) {
+	out := new(OperationResponse)
+	err := c.cc.Invoke(ctx, "/billing.Banker/Transfer", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bankerClient) Acquire(ctx context.Context, in *AcquireRequest, opts ...grpc.CallOption) (*OperationResponse, error) {
+	out := new(OperationResponse)
+	err := c.cc.Invoke(ctx, "/billing.Banker/Acquire", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bankerClient) Commit(ctx context.Context, in *CommitRequest, opts ...grpc.CallOption) (*OperationResponse, error) {
+	out := new(OperationResponse)
+	err := c.cc.Invoke(ctx, "/billing.Banker/Commit", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bankerClient) Rollback(ctx context.Context, in *RollbackRequest, opts ...grpc.CallOption) (*OperationResponse, error) {
+	out := new(OperationResponse)
+	err := c.cc.Invoke(ctx, "/billing.Banker/Rollback", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bankerClient) Balance(ctx context.Context, in *BalanceRequest, opts ...grpc.CallOption) (*BalanceResponse, error) {
+	out := new(BalanceResponse)
+	err := c.cc.Invoke(ctx, "/billing.Banker/Balance", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bankerClient) History(ctx context.Context, in *HistoryRequest, opts ...grpc.CallOption) (*HistoryResponse, error) {
+	out := new(HistoryResponse)
+	err := c.cc.Invoke(ctx, "/billing.Banker/History", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// BankerServer is the server API for Banker service.
+type BankerServer interface {
+	Credit(context.Context, *CreditRequest) (*OperationResponse, error)
+	Debit(context.Context, *DebitRequest) (*OperationResponse, error)
+	Transfer(context.Context, *TransferRequest) (*OperationResponse, error)
+	Acquire(context.Context, *AcquireRequest) (*OperationResponse, error)
+	Commit(context.Context, *CommitRequest) (*OperationResponse, error)
+	Rollback(context.Context, *RollbackRequest) (*OperationResponse, error)
+	Balance(context.Context, *BalanceRequest) (*BalanceResponse, error)
+	History(context.Context, *HistoryRequest) (*HistoryResponse, error)
+}
+
+// UnimplementedBankerServer can be embedded to have forward compatible implementations.
+type UnimplementedBankerServer struct {
+}
+
+func (*UnimplementedBankerServer) Credit(ctx context.Context, req *CreditRequest) (*OperationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Credit not implemented")
+}
+func (*UnimplementedBankerServer) Debit(ctx context.Context, req *DebitRequest) (*OperationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Debit not implemented")
+}
+func (*UnimplementedBankerServer) Transfer(ctx context.Context, req *TransferRequest) (*OperationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Transfer not implemented")
+}
+func (*UnimplementedBankerServer) Acquire(ctx context.Context, req *AcquireRequest) (*OperationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Acquire not implemented")
+}
+func (*UnimplementedBankerServer) Commit(ctx context.Context, req *CommitRequest) (*OperationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Commit not implemented")
+}
+func (*UnimplementedBankerServer) Rollback(ctx context.Context, req *RollbackRequest) (*OperationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Rollback not implemented")
+}
+func (*UnimplementedBankerServer) Balance(ctx context.Context, req *BalanceRequest) (*BalanceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Balance not implemented")
+}
+func (*UnimplementedBankerServer) History(ctx context.Context, req *HistoryRequest) (*HistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method History not implemented")
+}
+
+func RegisterBankerServer(s *grpc.Server, srv BankerServer) {
+	s.RegisterService(&_Banker_serviceDesc, srv)
+}
+
+func _Banker_Credit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreditRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BankerServer).Credit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/billing.Banker/Credit",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BankerServer).Credit(ctx, req.(*CreditRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Banker_Debit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DebitRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BankerServer).Debit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/billing.Banker/Debit",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BankerServer).Debit(ctx, req.(*DebitRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Banker_Transfer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TransferRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BankerServer).Transfer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/billing.Banker/Transfer",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BankerServer).Transfer(ctx, req.(*TransferRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Banker_Acquire_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AcquireRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BankerServer).Acquire(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/billing.Banker/Acquire",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BankerServer).Acquire(ctx, req.(*AcquireRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Banker_Commit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CommitRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BankerServer).Commit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/billing.Banker/Commit",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BankerServer).Commit(ctx, req.(*CommitRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Banker_Rollback_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RollbackRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BankerServer).Rollback(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/billing.Banker/Rollback",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BankerServer).Rollback(ctx, req.(*RollbackRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Banker_Balance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BalanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BankerServer).Balance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/billing.Banker/Balance",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BankerServer).Balance(ctx, req.(*BalanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Banker_History_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BankerServer).History(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/billing.Banker/History",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BankerServer).History(ctx, req.(*HistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _Banker_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "billing.Banker",
+	HandlerType: (*BankerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Credit",
+			Handler:    _Banker_Credit_Handler,
+		},
+		{
+			MethodName: "Debit",
+			Handler:    _Banker_Debit_Handler,
+		},
+		{
+			MethodName: "Transfer",
+			Handler:    _Banker_Transfer_Handler,
+		},
+		{
+			MethodName: "Acquire",
+			Handler:    _Banker_Acquire_Handler,
+		},
+		{
+			MethodName: "Commit",
+			Handler:    _Banker_Commit_Handler,
+		},
+		{
+			MethodName: "Rollback",
+			Handler:    _Banker_Rollback_Handler,
+		},
+		{
+			MethodName: "Balance",
+			Handler:    _Banker_Balance_Handler,
+		},
+		{
+			MethodName: "History",
+			Handler:    _Banker_History_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "billing.proto",
+}
@@ -0,0 +1,4 @@
+// Package pb contains gRPC contract of the billing service.
+package pb
+
+//go:generate protoc --go_out=plugins=grpc,paths=source_relative:. billing.proto
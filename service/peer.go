@@ -0,0 +1,81 @@
+package service
+
+import (
+	"billing/domain"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Other billing instance
+type Peer interface {
+	Deposit(ctx context.Context, uid int64, account uint32, amount float32) error
+}
+
+// Client of the REST API of other billing instance
+type httpPeer struct {
+	url    string
+	name   string // Name of this instance
+	client *http.Client
+}
+
+// Deposit amount to the account of the peer (see POST /accounts/{id}/deposits).
+// Uid is unique within this instance, so the peer registers it together with the name of the instance.
+// Repeated deposit with the same uid is reported by domain.ErrOperationIsDeprecated.
+func (p *httpPeer) Deposit(
+	ctx context.Context,
+	uid int64,
+	account uint32,
+	amount float32,
+) error {
+	body, err := json.Marshal(DepositRequest{Uid: uid, Peer: p.name, Amount: amount})
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s/accounts/%d/deposits", p.url, account)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var response Response
+	err = json.NewDecoder(resp.Body).Decode(&response)
+	if err != nil {
+		return fmt.Errorf("peer responded %s: %w", resp.Status, err)
+	}
+	if response.Status == domain.StatusOk {
+		return nil
+	}
+	if response.Error == nil {
+		return fmt.Errorf("peer responded %s without error", resp.Status)
+	}
+	if response.Error.Code == domain.ErrorCodeDeprecated {
+		return domain.ErrOperationIsDeprecated
+	}
+	return &domain.RemoteError{
+		Code:      response.Error.Code,
+		Message:   response.Error.Message,
+		Retryable: response.Error.Retryable,
+	}
+}
+
+// Create client of the billing instance by base url of its REST API.
+// Name identifies this instance for the peer.
+func NewHttpPeer(url string, name string, client *http.Client) Peer {
+	return &httpPeer{
+		url:    strings.TrimRight(url, "/"),
+		name:   name,
+		client: client,
+	}
+}
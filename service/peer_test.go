@@ -0,0 +1,98 @@
+package service
+
+import (
+	"billing/domain"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHttpPeer_Deposit(t *testing.T) {
+	type Src struct {
+		code int
+		body string
+	}
+
+	type Test struct {
+		src Src
+		dst error
+	}
+
+	tests := map[string]Test{
+		"Deposit must be accepted": {
+			src: Src{code: http.StatusOK, body: `{"Status":0}`},
+		},
+		"Repeated deposit must be deprecated": {
+			src: Src{
+				code: http.StatusConflict,
+				body: `{"Status":2,"Error":{"Code":"deprecated","Message":"operation is deprecated","Uid":1}}`,
+			},
+			dst: domain.ErrOperationIsDeprecated,
+		},
+		"Rejected deposit must keep the code": {
+			src: Src{
+				code: http.StatusNotFound,
+				body: `{"Status":4,"Error":{"Code":"not_found","Message":"account or hold is not found","Uid":1}}`,
+			},
+			dst: &domain.RemoteError{Code: "not_found", Message: "account or hold is not found"},
+		},
+		"Retryable failure must be retryable": {
+			src: Src{
+				code: http.StatusServiceUnavailable,
+				body: `{"Status":1,"Error":{"Code":"unavailable","Message":"database is unavailable","Retryable":true,"Uid":1}}`,
+			},
+			dst: &domain.RemoteError{Code: "unavailable", Message: "database is unavailable", Retryable: true},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var path, body string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				path = r.URL.Path
+				data, _ := io.ReadAll(r.Body)
+				body = string(data)
+				w.WriteHeader(test.src.code)
+				_, _ = w.Write([]byte(test.src.body))
+			}))
+			defer server.Close()
+
+			err := NewHttpPeer(server.URL+"/", "billing-a", server.Client()).Deposit(context.Background(), 1, 2001, 30)
+			assert.Equal(t, test.dst, err)
+			assert.Equal(t, "/accounts/2001/deposits", path)
+			assert.Contains(t, body, `"Uid":1`)
+			assert.Contains(t, body, `"Peer":"billing-a"`)
+		})
+	}
+
+	t.Run("Malformed response must be reported", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer server.Close()
+
+		err := NewHttpPeer(server.URL, "billing-a", server.Client()).Deposit(context.Background(), 1, 2001, 30)
+		assert.Error(t, err)
+		_, known := domain.DescribeError(err)
+		assert.False(t, known)
+	})
+}
+
+func TestHttpPeer_DepositToHandler(t *testing.T) {
+	manager := &managerMock{}
+	handler := NewHttpHandler(context.Background(), manager, manager, slog.New(slog.DiscardHandler))
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	err := NewHttpPeer(server.URL, "billing-a", server.Client()).Deposit(context.Background(), 1, 2001, 30)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"deposit billing-a 1 2001 30"}, manager.calls)
+
+	manager.err = domain.ErrOperationIsDeprecated
+	err = NewHttpPeer(server.URL, "billing-a", server.Client()).Deposit(context.Background(), 1, 2001, 30)
+	assert.Equal(t, domain.ErrOperationIsDeprecated, err)
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"sync"
+)
+
+// Fixed set of workers with bounded queue of the pending jobs
+type pool struct {
+	jobs  chan func()
+	slots chan struct{} // Jobs in progress and pending jobs
+	wg    sync.WaitGroup
+}
+
+// Enqueue job without waiting.
+// Returns false, if all workers are busy and the queue is full.
+func (p *pool) Submit(job func()) bool {
+	select {
+	case p.slots <- struct{}{}:
+		p.jobs <- job
+		return true
+	default:
+		return false
+	}
+}
+
+// Execute job by the worker and wait for completion.
+// Blocks caller while all workers are busy and the queue is full.
+func (p *pool) Run(job func()) {
+	done := make(chan struct{})
+	p.slots <- struct{}{}
+	p.jobs <- func() {
+		defer close(done)
+		job()
+	}
+	<-done
+}
+
+// Stop workers after completion of the queued jobs
+func (p *pool) Close() {
+	close(p.jobs)
+	p.wg.Wait()
+}
+
+func (p *pool) work() {
+	defer p.wg.Done()
+	for job := range p.jobs {
+		job()
+		<-p.slots
+	}
+}
+
+func newPool(workers, pending int) *pool {
+	p := &pool{
+		jobs:  make(chan func(), workers+pending),
+		slots: make(chan struct{}, workers+pending),
+	}
+
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+
+	return p
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"github.com/stretchr/testify/assert"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPool_Submit(t *testing.T) {
+	p := newPool(2, 1)
+
+	release := make(chan struct{})
+	started := make(chan struct{}, 2)
+	var done int32
+	job := func() {
+		started <- struct{}{}
+		<-release
+		atomic.AddInt32(&done, 1)
+	}
+
+	// Two jobs are taken by workers
+	assert.True(t, p.Submit(job))
+	<-started
+	assert.True(t, p.Submit(job))
+	<-started
+
+	// One job waits in the queue, next one is rejected
+	assert.True(t, p.Submit(job))
+	assert.False(t, p.Submit(job))
+
+	close(release)
+	p.Close()
+	assert.Equal(t, int32(3), atomic.LoadInt32(&done))
+}
+
+func TestPool_Run(t *testing.T) {
+	p := newPool(1, 0)
+	defer p.Close()
+
+	var active, peak int32
+	finished := make(chan struct{}, 3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			p.Run(func() {
+				n := atomic.AddInt32(&active, 1)
+				if n > atomic.LoadInt32(&peak) {
+					atomic.StoreInt32(&peak, n)
+				}
+				time.Sleep(5 * time.Millisecond)
+				atomic.AddInt32(&active, -1)
+			})
+			finished <- struct{}{}
+		}()
+	}
+
+	for i := 0; i < 3; i++ {
+		<-finished
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&peak))
+}
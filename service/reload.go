@@ -0,0 +1,131 @@
+package service
+
+import (
+	"billing/domain"
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// Holder of the current configuration.
+// Configuration is reloaded on SIGHUP and, if the interval is configured, on change of the config file.
+// Only reloadable options (see domain.IsReloadable) are applied, others require restart.
+type Reloader struct {
+	load    func() (domain.Configuration, error)
+	level   *slog.LevelVar
+	logger  *slog.Logger
+	current atomic.Pointer[domain.Configuration]
+	mu      sync.Mutex // Serializes reloads
+}
+
+// Get current configuration
+func (r *Reloader) Config() domain.Configuration {
+	return *r.current.Load()
+}
+
+// Get current timeouts of the operations
+func (r *Reloader) Timeout() domain.TimeoutOptions {
+	return r.current.Load().Timeout
+}
+
+// Load configuration and apply reloadable options.
+// Invalid configuration is rejected as a whole, current configuration is kept.
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next, err := r.load()
+	if err != nil {
+		r.logger.Error("Configuration is not reloaded", "error", err)
+		return err
+	}
+
+	prev := r.Config()
+	config := prev.Reload(next)
+
+	level, err := ParseLogLevel(config.Log.Level)
+	if err != nil {
+		r.logger.Error("Configuration is not reloaded", "error", err)
+		return err
+	}
+
+	changes := domain.DiffConfig(prev, next)
+	for _, change := range changes {
+		if domain.IsReloadable(change.Key) {
+			r.logger.Info("Option is changed", "key", change.Key, "old", change.Old, "new", change.New)
+		} else {
+			r.logger.Warn("Option requires restart", "key", change.Key, "old", change.Old, "new", change.New)
+		}
+	}
+
+	r.level.Set(level)
+	r.current.Store(&config)
+	r.logger.Info("Configuration is reloaded", "changes", len(changes))
+	return nil
+}
+
+// Reload configuration on SIGHUP and on change of the config file until the context is done
+func (r *Reloader) Run(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	config := r.Config()
+	var tick <-chan time.Time
+	if config.ReloadInterval > 0 && config.File != "" {
+		ticker := time.NewTicker(time.Duration(config.ReloadInterval) * time.Second)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	modified := modTime(config.File)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			_ = r.Reload()
+		case <-tick:
+			t := modTime(config.File)
+			if t.Equal(modified) {
+				continue
+			}
+			modified = t
+			_ = r.Reload()
+		}
+	}
+}
+
+// Get time of the last modification of the file (zero, if the file is not available)
+func modTime(path string) time.Time {
+	if path == "" {
+		return time.Time{}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// Create holder of the loaded configuration.
+// Function load gets the whole new configuration, level controls the logger.
+func NewReloader(
+	config domain.Configuration,
+	load func() (domain.Configuration, error),
+	level *slog.LevelVar,
+	logger *slog.Logger,
+) *Reloader {
+	r := &Reloader{
+		load:   load,
+		level:  level,
+		logger: logger,
+	}
+	r.current.Store(&config)
+	return r
+}
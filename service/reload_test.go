@@ -0,0 +1,97 @@
+package service
+
+import (
+	"billing/domain"
+	"errors"
+	"github.com/adverax/echo/database/sql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"testing"
+)
+
+func TestReloader_Reload(t *testing.T) {
+	type Src struct {
+		next domain.Configuration
+		err  error
+	}
+
+	type Dst struct {
+		config domain.Configuration
+		level  slog.Level
+		err    bool
+	}
+
+	type Test struct {
+		src Src
+		dst Dst
+	}
+
+	prev := domain.DefaultConfig()
+	prev.Database.Nodes = []*sql.DSN{{}}
+
+	next := prev
+	next.ShutdownTimeout = 5
+	next.Timeout = domain.TimeoutOptions{Default: 100, Operations: map[string]int{"transfer": 200}}
+	next.Log.Level = "debug"
+
+	structural := prev
+	structural.Broker.Prefix = "billing."
+	structural.Log.Format = "json"
+
+	tests := map[string]Test{
+		"Reloadable options must be applied": {
+			src: Src{
+				next: next,
+			},
+			dst: Dst{
+				config: next,
+				level:  slog.LevelDebug,
+			},
+		},
+		"Structural options must be kept": {
+			src: Src{
+				next: structural,
+			},
+			dst: Dst{
+				config: prev,
+				level:  slog.LevelInfo,
+			},
+		},
+		"Invalid configuration must be rejected": {
+			src: Src{
+				next: next,
+				err:  errors.New("invalid configuration"),
+			},
+			dst: Dst{
+				config: prev,
+				level:  slog.LevelInfo,
+				err:    true,
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			level := new(slog.LevelVar)
+			logger, err := NewLogger(io.Discard, prev.Log, level)
+			require.NoError(t, err)
+
+			r := NewReloader(
+				prev,
+				func() (domain.Configuration, error) {
+					return test.src.next, test.src.err
+				},
+				level,
+				logger,
+			)
+
+			err = r.Reload()
+			assert.Equal(t, test.dst.err, err != nil)
+			assert.Equal(t, test.dst.config, r.Config())
+			assert.Equal(t, test.dst.config.Timeout, r.Timeout())
+			assert.Equal(t, test.dst.level, level.Level())
+		})
+	}
+}
@@ -4,108 +4,325 @@ import (
 	"billing/domain"
 	"billing/manager/banker"
 	"context"
-	"github.com/adverax/echo/data"
-	"github.com/adverax/echo/log"
-	"github.com/nats-io/go-nats"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"runtime/debug"
+	"sync"
 	"syscall"
+	"time"
 )
 
+// Message, that can not be processed
+type DeadLetter struct {
+	Subject string    // Original subject
+	Data    []byte    // Raw payload
+	Reason  string    // Machine-readable reason (see domain.ErrorCode*)
+	Error   string    // Error details
+	Time    time.Time // Time of the failure
+}
+
+// Deadline for export of the buffered spans on shutdown
+const tracingShutdownTimeout = 5 * time.Second
+
+type endpoint struct {
+	request func() Request
+	execute func(ctx context.Context, r Request) error
+}
+
+// Subscription of the banker endpoints to the transport
+type Subscription interface {
+	// Stop receiving new messages and wait for replies to the received ones
+	Drain() error
+}
+
+type server struct {
+	transport Transport
+	manager   banker.Manager
+	escrow    banker.Escrow
+	options   domain.BrokerOptions
+	logger    *slog.Logger
+	active    sync.WaitGroup // Messages in progress
+	pools     []*pool
+}
+
 func Bootstrap(
 	ctx context.Context,
 	manager banker.Manager,
-	options domain.BrokerOptions,
-	logger log.Logger,
+	escrow banker.Escrow,
+	reloader *Reloader,
+	metrics *Metrics,
+	health *Health,
+	logger *slog.Logger,
 ) error {
-	nc, err := nats.Connect(options.Server)
+	// Context of the operations. It is cancelled, when the deadline of the shutdown is exceeded.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	config := reloader.Config()
+	go reloader.Run(ctx)
+
+	shutdownTracing, err := SetupTracing(ctx, config.Tracing)
 	if err != nil {
 		return err
 	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+		defer cancel()
+		err := shutdownTracing(ctx)
+		if err != nil {
+			logger.Error("Spans are not exported", "error", err)
+		}
+	}()
+
+	manager = NewMetricsManager(
+		NewTracingManager(
+			NewReloadableTimeoutManager(manager, reloader.Timeout),
+		),
+		metrics,
+	)
+	escrow = NewMetricsEscrow(
+		NewTracingEscrow(
+			NewReloadableTimeoutEscrow(escrow, reloader.Timeout),
+		),
+		metrics,
+	)
 
-	c, _ := nats.NewEncodedConn(nc, nats.JSON_ENCODER)
+	transport, err := newTransport(ctx, config.Broker, logger)
 	if err != nil {
 		return err
 	}
-	defer c.Close()
+	defer transport.Close()
+
+	if checker, ok := transport.(Checker); ok {
+		health.Register("broker", checker.Check)
+	}
+
+	if pc, ok := transport.(PendingCounter); ok {
+		err = metrics.Register(NewPendingCollector(pc))
+		if err != nil {
+			return err
+		}
+	}
 
-	err = subscribeAll(ctx, c, manager, logger)
+	subscription, err := Subscribe(ctx, transport, manager, escrow, config.Broker, logger)
 	if err != nil {
 		return err
 	}
+	stops := []func() error{subscription.Drain}
+
+	if config.Http.Listen != "" {
+		hs := &http.Server{
+			Addr:    config.Http.Listen,
+			Handler: NewHttpHandler(ctx, manager, escrow, logger),
+		}
+		go func() {
+			err := hs.ListenAndServe()
+			if err != nil && err != http.ErrServerClosed {
+				logger.Error("REST API server failed", "error", err)
+			}
+		}()
+		stops = append(stops, func() error {
+			err := hs.Shutdown(ctx)
+			if err == context.Canceled {
+				return hs.Close()
+			}
+			return err
+		})
+	}
+
+	if config.Metrics.Listen != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler())
+		mux.Handle("/healthz", health.Handler())
+		mux.Handle("/readyz", health.Handler())
+		ms := &http.Server{
+			Addr:    config.Metrics.Listen,
+			Handler: mux,
+		}
+		go func() {
+			err := ms.ListenAndServe()
+			if err != nil && err != http.ErrServerClosed {
+				logger.Error("Metrics server failed", "error", err)
+			}
+		}()
+		defer ms.Close()
+	}
+
+	if config.Grpc.Listen != "" {
+		lis, err := net.Listen("tcp", config.Grpc.Listen)
+		if err != nil {
+			return err
+		}
+		gs := NewGrpcServer(manager, logger)
+		go func() {
+			err := gs.Serve(lis)
+			if err != nil {
+				logger.Error("gRPC server failed", "error", err)
+			}
+		}()
+		stops = append(stops, func() error {
+			done := make(chan struct{})
+			go func() {
+				gs.GracefulStop()
+				close(done)
+			}()
+			select {
+			case <-done:
+			case <-ctx.Done():
+				gs.Stop()
+				<-done
+			}
+			return nil
+		})
+	}
 
 	logger.Info("Service is started")
-	abort := make(chan os.Signal)
+	abort := make(chan os.Signal, 1)
 	signal.Notify(abort, syscall.SIGINT, syscall.SIGTERM)
-	<-abort
+	defer signal.Stop(abort)
+
+	select {
+	case <-abort:
+	case <-ctx.Done():
+	}
+
+	logger.Info("Service is stopping")
+	health.Stop()
+	shutdown(
+		time.Duration(reloader.Config().ShutdownTimeout)*time.Second,
+		cancel,
+		logger,
+		stops...,
+	)
+	logger.Info("Service is stopped")
 
 	return nil
 }
 
-func subscribeAll(
+// Stop receiving of the requests and wait for the operations in progress.
+// When the deadline is exceeded, operations are cancelled and their errors are still replied.
+func shutdown(
+	timeout time.Duration,
+	cancel context.CancelFunc,
+	logger *slog.Logger,
+	stops ...func() error,
+) {
+	var wg sync.WaitGroup
+	for _, stop := range stops {
+		stop := stop
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := stop()
+			if err != nil {
+				logger.Error("Service is not stopped gracefully", "error", err)
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return
+	case <-time.After(timeout):
+	}
+
+	logger.Warn("Deadline of the shutdown is exceeded, operations in progress are cancelled")
+	cancel()
+	<-done
+}
+
+func newTransport(
+	ctx context.Context,
+	options domain.BrokerOptions,
+	logger *slog.Logger,
+) (Transport, error) {
+	switch options.Transport {
+	case "", "nats":
+		nc, err := ConnectNats(ctx, options.NatsServers(), options.Nats, logger)
+		if err != nil {
+			return nil, err
+		}
+		return NewNatsTransport(nc, options.Nats), nil
+	case "kafka":
+		return NewKafkaTransport(options.Kafka, logger)
+	case "amqp":
+		return NewAmqpTransport(options, logger)
+	}
+
+	return nil, fmt.Errorf("unknown transport %q", options.Transport)
+}
+
+// Subscribe endpoints of the banker and the escrow to the transport
+func Subscribe(
 	ctx context.Context,
-	c *nats.EncodedConn,
+	transport Transport,
 	manager banker.Manager,
-	logger log.Logger,
-) error {
-	return subscribe(
-		c,
-		map[string]interface{}{
-			"bank.credit": func(subj, reply string, r *CreditRequest) {
-				defer handlePanic(logger)
-				err := manager.Credit(ctx, r.Uid, r.Account, r.Amount)
-				_ = c.Publish(reply,
-					CreditResponse{Status: getStatus(err, logger)},
-				)
-			},
-			"bank.debit": func(subj, reply string, r *DebitRequest) {
-				defer handlePanic(logger)
-				err := manager.Debit(ctx, r.Uid, r.Account, r.Amount)
-				_ = c.Publish(reply,
-					DebitResponse{Status: getStatus(err, logger)},
-				)
-			},
-			"bank.transfer": func(subj, reply string, r *TransferRequest) {
-				defer handlePanic(logger)
-				err := manager.Transfer(ctx, r.Uid, r.Src, r.Dst, r.Amount)
-				_ = c.Publish(reply,
-					TransferResponse{Status: getStatus(err, logger)},
-				)
-			},
-			"bank.acquire": func(subj, reply string, r *AcquireRequest) {
-				defer handlePanic(logger)
-				err := manager.Acquire(ctx, r.Uid, r.Account, r.Amount)
-				_ = c.Publish(reply,
-					AcquireResponse{Status: getStatus(err, logger)},
-				)
-			},
-			"bank.commit": func(subj, reply string, r *CommitRequest) {
-				defer handlePanic(logger)
-				err := manager.Commit(ctx, r.Uid, r.Account)
-				_ = c.Publish(reply,
-					CommitResponse{Status: getStatus(err, logger)},
-				)
-			},
-			"bank.rollback": func(subj, reply string, r *RollbackRequest) {
-				defer handlePanic(logger)
-				err := manager.Rollback(ctx, r.Uid, r.Account)
-				_ = c.Publish(reply,
-					RollbackResponse{Status: getStatus(err, logger)},
-				)
-			},
-		},
-	)
+	escrow banker.Escrow,
+	options domain.BrokerOptions,
+	logger *slog.Logger,
+) (Subscription, error) {
+	s := &server{
+		transport: transport,
+		manager:   manager,
+		escrow:    escrow,
+		options:   options,
+		logger:    logger,
+	}
+
+	err := s.subscribeAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *server) Drain() error {
+	err := s.transport.Drain()
+	s.active.Wait()
+
+	for _, p := range s.pools {
+		p.Close()
+	}
+	s.pools = nil
+
+	return err
 }
 
-func subscribe(
-	c *nats.EncodedConn,
-	handlers map[string]interface{},
+func (s *server) subscribeAll(ctx context.Context) error {
+	return s.subscribe(ctx, allEndpoints(s.manager, s.escrow))
+}
+
+func (s *server) subscribe(
+	ctx context.Context,
+	handlers map[string]endpoint,
 ) error {
-	for key, handler := range handlers {
-		_, err := c.QueueSubscribe(
-			key,
-			key,
-			handler,
+	for name := range s.options.Endpoints {
+		if _, ok := handlers[name]; !ok {
+			return fmt.Errorf("unknown endpoint %q", name)
+		}
+	}
+
+	for name, handler := range handlers {
+		if s.options.Endpoints[name].Disabled {
+			continue
+		}
+
+		err := s.transport.Subscribe(
+			s.options.Subject(name),
+			s.options.Queue(name),
+			s.dispatcher(ctx, name, handler),
 		)
 		if err != nil {
 			return err
@@ -115,27 +332,335 @@ func subscribe(
 	return nil
 }
 
-func getStatus(err error, logger log.Logger) uint8 {
+// Get handler of the messages of the endpoint.
+// Messages are handled by the worker pool of the endpoint, if it is configured.
+// When the pool is overloaded, message is rejected with retryable error,
+// so the caller (or broker) can repeat it later.
+func (s *server) dispatcher(
+	ctx context.Context,
+	name string,
+	handler endpoint,
+) Handler {
+	workers, pending := s.options.Pool(name)
+	if workers <= 0 {
+		return func(msg Message) {
+			s.active.Add(1)
+			defer s.active.Done()
+			s.handle(ctx, handler, msg)
+		}
+	}
+
+	p := newPool(workers, pending)
+	s.pools = append(s.pools, p)
+
+	return func(msg Message) {
+		s.active.Add(1)
+		job := func() {
+			defer s.active.Done()
+			s.handle(ctx, handler, msg)
+		}
+
+		if _, ok := msg.(Synchronous); ok {
+			p.Run(job)
+			return
+		}
+
+		if !p.Submit(job) {
+			defer s.active.Done()
+			s.reject(ctx, handler, msg, domain.ErrOverloaded)
+		}
+	}
+}
+
+func (s *server) handle(
+	ctx context.Context,
+	handler endpoint,
+	msg Message,
+) {
+	ctx, span := startMessageSpan(ctx, msg)
+	logger := s.logger.With("subject", msg.Subject())
+	response, failure := s.process(ctx, handler, msg.Data(), logger)
+	if failure != nil {
+		s.deadLetter(ctx, msg, response, failure)
+	}
+
+	s.reply(ctx, msg, response)
+	endMessageSpan(span, response)
+}
+
+// Reply error without execution of the request
+func (s *server) reject(
+	ctx context.Context,
+	handler endpoint,
+	msg Message,
+	err error,
+) {
+	start := time.Now()
+	logger := s.logger.With("subject", msg.Subject())
+
+	var header Header
+	r := handler.request()
+	if json.Unmarshal(msg.Data(), r) == nil {
+		header = r.Header()
+		logger = logger.With(requestAttrs(r)...)
+	}
+
+	ctx, span := startMessageSpan(ctx, msg)
+	response := respond(err, header)
+	logResult(ctx, logger, err, response, start)
+	s.reply(ctx, msg, response)
+	endMessageSpan(span, response)
+}
+
+// Send response to the requester with the trace context.
+// Message with retryable error is returned to the broker, if it is supported.
+func (s *server) reply(
+	ctx context.Context,
+	msg Message,
+	response Response,
+) {
+	if response.Error != nil && response.Error.Retryable {
+		if r, ok := msg.(Requeuer); ok && r.Requeue() {
+			return
+		}
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		s.logger.Error("Response is not encoded", "subject", msg.Subject(), "error", err)
+		return
+	}
+
+	if hc, ok := msg.(HeaderCarrier); ok {
+		err = hc.ReplyWithHeaders(data, traceHeaders(ctx))
+	} else {
+		err = msg.Reply(data)
+	}
+	if err != nil {
+		s.logger.Error("Response is not sent", "subject", msg.Subject(), "error", err)
+	}
+}
+
+// Process raw request.
+// Returns non nil failure, if the message must be sent to the dead letter subject.
+func (s *server) process(
+	ctx context.Context,
+	handler endpoint,
+	data []byte,
+	logger *slog.Logger,
+) (response Response, failure error) {
+	r := handler.request()
+	err := json.Unmarshal(data, r)
+	if err != nil {
+		logger.Warn("Message is malformed", "error", err)
+		return respond(domain.ErrMalformedRequest, Header{}), err
+	}
+
+	return execute(ctx, handler, r, logger)
+}
+
+// Execute decoded request and log its result with the fields of the request.
+// Returns non nil failure, if the request causes panic.
+func execute(
+	ctx context.Context,
+	handler endpoint,
+	r Request,
+	logger *slog.Logger,
+) (response Response, failure error) {
+	header := r.Header()
+	logger = logger.With(requestAttrs(r)...)
+	start := time.Now()
+
+	defer func() {
+		if e := recover(); e != nil {
+			failure = fmt.Errorf("panic: %v", e)
+			response = respond(failure, header)
+			logResult(ctx, logger, failure, response, start, "stack", string(debug.Stack()))
+		}
+	}()
+
+	if !header.Deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, header.Deadline)
+		defer cancel()
+	}
+
+	err := r.Validate()
+	if err == nil {
+		// Caller is not waiting for the result anymore
+		err = ctx.Err()
+	}
 	if err == nil {
-		return domain.StatusOk
+		err = handler.execute(ctx, r)
 	}
 
-	switch err {
-	case domain.ErrNoMoney:
-		return domain.StatusNoMoney
-	case domain.ErrOperationIsDeprecated:
-		return domain.StatusDeprecated
-	case data.ErrNoMatch:
-		return domain.StatusNotFound
-	default:
-		logger.Error(err)
-		return domain.StatusUnknownError
+	response = respond(err, header)
+	logResult(ctx, logger, err, response, start)
+	return response, nil
+}
+
+func respond(err error, header Header) Response {
+	status, e := getResult(err, header.Uid, header.CorrelationId)
+	return Response{Status: status, Error: e}
+}
+
+func (s *server) deadLetter(
+	ctx context.Context,
+	msg Message,
+	response Response,
+	failure error,
+) {
+	if s.options.DeadLetter == "" {
+		return
+	}
+
+	letter := DeadLetter{
+		Subject: msg.Subject(),
+		Data:    msg.Data(),
+		Error:   failure.Error(),
+		Time:    time.Now(),
+	}
+	if response.Error != nil {
+		letter.Reason = response.Error.Code
+	}
+
+	data, err := json.Marshal(letter)
+	if err != nil {
+		s.logger.Error("Dead letter is not encoded", "subject", msg.Subject(), "error", err)
+		return
+	}
+
+	if hp, ok := s.transport.(HeaderPublisher); ok {
+		err = hp.PublishWithHeaders(s.options.DeadLetter, data, traceHeaders(ctx))
+	} else {
+		err = s.transport.Publish(s.options.DeadLetter, data)
+	}
+	if err != nil {
+		s.logger.Error("Dead letter is not published", "subject", msg.Subject(), "error", err)
 	}
 }
 
-func handlePanic(logger log.Logger) {
-	e := recover()
-	if e != nil {
-		logger.Error(e)
+// Get endpoints of the banker by name
+func endpoints(manager banker.Manager) map[string]endpoint {
+	return map[string]endpoint{
+		"credit": {
+			request: func() Request { return new(CreditRequest) },
+			execute: func(ctx context.Context, req Request) error {
+				r := req.(*CreditRequest)
+				return manager.Credit(ctx, r.Uid, r.Account, r.Amount)
+			},
+		},
+		"debit": {
+			request: func() Request { return new(DebitRequest) },
+			execute: func(ctx context.Context, req Request) error {
+				r := req.(*DebitRequest)
+				return manager.Debit(ctx, r.Uid, r.Account, r.Amount)
+			},
+		},
+		"transfer": {
+			request: func() Request { return new(TransferRequest) },
+			execute: func(ctx context.Context, req Request) error {
+				r := req.(*TransferRequest)
+				return manager.Transfer(ctx, r.Uid, r.Src, r.Dst, r.Amount)
+			},
+		},
+		"deposit": {
+			request: func() Request { return new(DepositRequest) },
+			execute: func(ctx context.Context, req Request) error {
+				r := req.(*DepositRequest)
+				return manager.Deposit(ctx, r.Peer, r.Uid, r.Account, r.Amount)
+			},
+		},
+		"acquire": {
+			request: func() Request { return new(AcquireRequest) },
+			execute: func(ctx context.Context, req Request) error {
+				r := req.(*AcquireRequest)
+				return manager.Acquire(ctx, r.Uid, r.Account, r.Dst, r.Amount)
+			},
+		},
+		"commit": {
+			request: func() Request { return new(CommitRequest) },
+			execute: func(ctx context.Context, req Request) error {
+				r := req.(*CommitRequest)
+				return manager.Commit(ctx, r.Uid, r.Account)
+			},
+		},
+		"rollback": {
+			request: func() Request { return new(RollbackRequest) },
+			execute: func(ctx context.Context, req Request) error {
+				r := req.(*RollbackRequest)
+				return manager.Rollback(ctx, r.Uid, r.Account)
+			},
+		},
+		"reverse": {
+			request: func() Request { return new(ReverseRequest) },
+			execute: func(ctx context.Context, req Request) error {
+				r := req.(*ReverseRequest)
+				return manager.Reverse(ctx, &domain.Reversal{
+					Uid:      r.Uid,
+					Original: r.Original,
+					Account:  r.Account,
+					Dst:      r.Dst,
+					Amount:   r.Amount,
+				})
+			},
+		},
+	}
+}
+
+// Get endpoints of the escrow by name
+func escrowEndpoints(escrow banker.Escrow) map[string]endpoint {
+	return map[string]endpoint{
+		"escrow.open": {
+			request: func() Request { return new(EscrowOpenRequest) },
+			execute: func(ctx context.Context, req Request) error {
+				r := req.(*EscrowOpenRequest)
+				return escrow.Open(ctx, r.Uid, r.Escrow, r.Account, r.Amount)
+			},
+		},
+		"escrow.release": {
+			request: func() Request { return new(EscrowReleaseRequest) },
+			execute: func(ctx context.Context, req Request) error {
+				r := req.(*EscrowReleaseRequest)
+				return escrow.Release(ctx, r.Uid, r.Escrow, r.Account, r.Payouts)
+			},
+		},
+		"escrow.refund": {
+			request: func() Request { return new(EscrowRefundRequest) },
+			execute: func(ctx context.Context, req Request) error {
+				r := req.(*EscrowRefundRequest)
+				return escrow.Refund(ctx, r.Uid, r.Escrow, r.Account, r.Amount)
+			},
+		},
+	}
+}
+
+// Get endpoints of the banker and the escrow (if it is not nil) by name
+func allEndpoints(manager banker.Manager, escrow banker.Escrow) map[string]endpoint {
+	handlers := endpoints(manager)
+	for name, handler := range escrowEndpoints(escrow) {
+		handlers[name] = handler
+	}
+	return handlers
+}
+
+// Get status and error payload of the operation
+func getResult(
+	err error,
+	uid int64,
+	correlationId string,
+) (uint8, *Error) {
+	if err == nil {
+		return domain.StatusOk, nil
+	}
+
+	info, _ := domain.DescribeError(err)
+
+	return info.Status, &Error{
+		Code:          info.Code,
+		Message:       info.Message,
+		Retryable:     info.Retryable,
+		Uid:           uid,
+		CorrelationId: correlationId,
 	}
 }
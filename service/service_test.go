@@ -0,0 +1,358 @@
+package service
+
+import (
+	"billing/domain"
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+type managerMock struct {
+	err     error
+	panic   interface{}
+	balance float32
+	history []*domain.HistoryRecord
+	calls   []string
+}
+
+func (m *managerMock) result(call string) error {
+	m.calls = append(m.calls, call)
+	if m.panic != nil {
+		panic(m.panic)
+	}
+	return m.err
+}
+
+func (m *managerMock) Credit(ctx context.Context, uid int64, account uint32, amount float32) error {
+	return m.result(fmt.Sprintf("credit %d %d %v", uid, account, amount))
+}
+
+func (m *managerMock) Debit(ctx context.Context, uid int64, account uint32, amount float32) error {
+	return m.result(fmt.Sprintf("debit %d %d %v", uid, account, amount))
+}
+
+func (m *managerMock) Transfer(ctx context.Context, uid int64, src, dst uint32, amount float32) error {
+	return m.result(fmt.Sprintf("transfer %d %d %d %v", uid, src, dst, amount))
+}
+
+func (m *managerMock) Deposit(ctx context.Context, peer string, uid int64, account uint32, amount float32) error {
+	return m.result(fmt.Sprintf("deposit %s %d %d %v", peer, uid, account, amount))
+}
+
+func (m *managerMock) Acquire(ctx context.Context, uid int64, account uint32, dst uint32, amount float32) error {
+	return m.result(fmt.Sprintf("acquire %d %d %d %v", uid, account, dst, amount))
+}
+
+func (m *managerMock) Commit(ctx context.Context, uid int64, account uint32) error {
+	return m.result(fmt.Sprintf("commit %d %d", uid, account))
+}
+
+func (m *managerMock) Rollback(ctx context.Context, uid int64, account uint32) error {
+	return m.result(fmt.Sprintf("rollback %d %d", uid, account))
+}
+
+func (m *managerMock) Reverse(ctx context.Context, reversal *domain.Reversal) error {
+	return m.result(fmt.Sprintf("reverse %d %d %d %d %v", reversal.Uid, reversal.Original, reversal.Account, reversal.Dst, reversal.Amount))
+}
+
+func (m *managerMock) Balance(ctx context.Context, account uint32) (float32, error) {
+	err := m.result(fmt.Sprintf("balance %d", account))
+	return m.balance, err
+}
+
+func (m *managerMock) History(ctx context.Context, account uint32, offset, limit int) ([]*domain.HistoryRecord, error) {
+	err := m.result(fmt.Sprintf("history %d %d %d", account, offset, limit))
+	return m.history, err
+}
+
+func (m *managerMock) Open(ctx context.Context, uid int64, id int64, account uint32, amount float32) error {
+	return m.result(fmt.Sprintf("escrow.open %d %d %d %v", uid, id, account, amount))
+}
+
+func (m *managerMock) Release(ctx context.Context, uid int64, id int64, account uint32, payouts []domain.Payout) error {
+	return m.result(fmt.Sprintf("escrow.release %d %d %d %v", uid, id, account, payouts))
+}
+
+func (m *managerMock) Refund(ctx context.Context, uid int64, id int64, account uint32, amount float32) error {
+	return m.result(fmt.Sprintf("escrow.refund %d %d %d %v", uid, id, account, amount))
+}
+
+func (m *managerMock) Get(ctx context.Context, id int64, account uint32) (*domain.Escrow, []*domain.EscrowRecord, error) {
+	err := m.result(fmt.Sprintf("escrow.get %d %d", id, account))
+	if err != nil {
+		return nil, nil, err
+	}
+	return &domain.Escrow{Id: id, Account: account, Amount: 30, Balance: 10}, nil, nil
+}
+
+type transportMock struct {
+	subscriptions map[string]string
+}
+
+func (t *transportMock) Subscribe(subject, queue string, handler Handler) error {
+	t.subscriptions[subject] = queue
+	return nil
+}
+
+func (t *transportMock) Publish(subject string, data []byte) error {
+	return nil
+}
+
+func (t *transportMock) Drain() error {
+	return nil
+}
+
+func (t *transportMock) Close() error {
+	return nil
+}
+
+func TestEndpointNames(t *testing.T) {
+	manager := &managerMock{}
+	var names []string
+	for name := range allEndpoints(manager, manager) {
+		names = append(names, name)
+	}
+	assert.ElementsMatch(t, domain.EndpointNames, names, "options must be known for every endpoint")
+}
+
+func TestSubscribe_Options(t *testing.T) {
+	type Test struct {
+		src domain.BrokerOptions
+		dst map[string]string
+		err bool
+	}
+
+	tests := map[string]Test{
+		"Subject must be used as queue group by default": {
+			src: domain.BrokerOptions{
+				Prefix: "bank.",
+			},
+			dst: map[string]string{
+				"bank.credit":   "bank.credit",
+				"bank.debit":    "bank.debit",
+				"bank.transfer": "bank.transfer",
+				"bank.deposit":  "bank.deposit",
+				"bank.acquire":  "bank.acquire",
+				"bank.commit":   "bank.commit",
+				"bank.rollback": "bank.rollback",
+				"bank.reverse":  "bank.reverse",
+
+				"bank.escrow.open":    "bank.escrow.open",
+				"bank.escrow.release": "bank.escrow.release",
+				"bank.escrow.refund":  "bank.escrow.refund",
+			},
+		},
+		"Queue groups must be configurable": {
+			src: domain.BrokerOptions{
+				Prefix:      "dev.bank.",
+				QueuePrefix: "billing.",
+				Endpoints: map[string]domain.EndpointOptions{
+					"credit":   {Queue: "deposits"},
+					"transfer": {Disabled: true},
+					"deposit":  {Disabled: true},
+					"acquire":  {Disabled: true},
+					"commit":   {Disabled: true},
+					"rollback": {Disabled: true},
+					"reverse":  {Disabled: true},
+
+					"escrow.open":    {Queue: "escrows"},
+					"escrow.release": {Disabled: true},
+				},
+			},
+			dst: map[string]string{
+				"dev.bank.credit":        "deposits",
+				"dev.bank.debit":         "billing.debit",
+				"dev.bank.escrow.open":   "escrows",
+				"dev.bank.escrow.refund": "billing.escrow.refund",
+			},
+		},
+		"Unknown endpoint must be rejected": {
+			src: domain.BrokerOptions{
+				Endpoints: map[string]domain.EndpointOptions{
+					"refund": {Disabled: true},
+				},
+			},
+			dst: map[string]string{},
+			err: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			transport := &transportMock{subscriptions: map[string]string{}}
+			manager := &managerMock{}
+			_, err := Subscribe(
+				context.Background(),
+				transport,
+				manager,
+				manager,
+				test.src,
+				slog.New(slog.DiscardHandler),
+			)
+			assert.Equal(t, test.err, err != nil)
+			assert.Equal(t, test.dst, transport.subscriptions)
+		})
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var stopped []string
+	done := make(chan struct{})
+	go func() {
+		shutdown(
+			10*time.Millisecond,
+			cancel,
+			slog.New(slog.DiscardHandler),
+			func() error {
+				<-ctx.Done()
+				stopped = append(stopped, "cancelled")
+				return nil
+			},
+		)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("shutdown must cancel operations after the deadline")
+	}
+	assert.Equal(t, []string{"cancelled"}, stopped)
+}
+
+func TestServer_Process(t *testing.T) {
+	type Src struct {
+		endpoint string
+		data     string
+		manager  managerMock
+	}
+
+	type Dst struct {
+		response Response
+		failed   bool
+	}
+
+	type Test struct {
+		src Src
+		dst Dst
+	}
+
+	tests := map[string]Test{
+		"Valid request must be executed": {
+			src: Src{
+				endpoint: "credit",
+				data:     `{"uid":1,"account":1,"amount":10}`,
+			},
+			dst: Dst{
+				response: Response{Status: domain.StatusOk},
+			},
+		},
+		"Malformed request must be dead lettered": {
+			src: Src{
+				endpoint: "debit",
+				data:     `{"uid":1,"account":`,
+			},
+			dst: Dst{
+				response: Response{
+					Status: domain.StatusInvalidRequest,
+					Error: &Error{
+						Code:    "malformed_request",
+						Message: "request cannot be decoded",
+					},
+				},
+				failed: true,
+			},
+		},
+		"Invalid request must be rejected": {
+			src: Src{
+				endpoint: "transfer",
+				data:     `{"uid":1,"src":1,"dst":1,"amount":10,"correlationId":"abc"}`,
+			},
+			dst: Dst{
+				response: Response{
+					Status: domain.StatusInvalidRequest,
+					Error: &Error{
+						Code:          "same_account",
+						Message:       "source and destination accounts must differ",
+						Uid:           1,
+						CorrelationId: "abc",
+					},
+				},
+			},
+		},
+		"Expired request must not be executed": {
+			src: Src{
+				endpoint: "debit",
+				data:     `{"uid":4,"account":1,"amount":10,"deadline":"2000-01-01T00:00:00Z"}`,
+			},
+			dst: Dst{
+				response: Response{
+					Status: domain.StatusTimeout,
+					Error: &Error{
+						Code:      domain.ErrorCodeTimeout,
+						Message:   "operation timed out",
+						Retryable: true,
+						Uid:       4,
+					},
+				},
+			},
+		},
+		"Banker error must be reported": {
+			src: Src{
+				endpoint: "acquire",
+				data:     `{"uid":2,"account":1,"amount":10}`,
+				manager:  managerMock{err: domain.ErrNoMoney},
+			},
+			dst: Dst{
+				response: Response{
+					Status: domain.StatusNoMoney,
+					Error: &Error{
+						Code:    domain.ErrorCodeNoMoney,
+						Message: "insufficient funds",
+						Uid:     2,
+					},
+				},
+			},
+		},
+		"Panic must be reported and dead lettered": {
+			src: Src{
+				endpoint: "commit",
+				data:     `{"uid":3,"account":1}`,
+				manager:  managerMock{panic: "boom"},
+			},
+			dst: Dst{
+				response: Response{
+					Status: domain.StatusUnknownError,
+					Error: &Error{
+						Code:    domain.ErrorCodeUnknown,
+						Message: "internal error",
+						Uid:     3,
+					},
+				},
+				failed: true,
+			},
+		},
+	}
+
+	ctx := context.Background()
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			logger := slog.New(slog.DiscardHandler)
+			s := &server{logger: logger}
+			handler := endpoints(&test.src.manager)[test.src.endpoint]
+			response, failure := s.process(ctx, handler, []byte(test.src.data), logger)
+			assert.Equal(t, test.dst.response, response)
+			assert.Equal(t, test.dst.failed, failure != nil)
+			if test.dst.response.Status == domain.StatusTimeout {
+				assert.Empty(t, test.src.manager.calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package service
+
+import (
+	"billing/domain"
+	"billing/manager/banker"
+	"context"
+)
+
+// Banker with limited duration of the operations
+type timeoutManager struct {
+	banker.Manager
+	options func() domain.TimeoutOptions
+}
+
+func (m *timeoutManager) Credit(
+	ctx context.Context,
+	uid int64,
+	account uint32,
+	amount float32,
+) error {
+	ctx, cancel := m.context(ctx, "credit")
+	defer cancel()
+	return expired(ctx, m.Manager.Credit(ctx, uid, account, amount))
+}
+
+func (m *timeoutManager) Debit(
+	ctx context.Context,
+	uid int64,
+	account uint32,
+	amount float32,
+) error {
+	ctx, cancel := m.context(ctx, "debit")
+	defer cancel()
+	return expired(ctx, m.Manager.Debit(ctx, uid, account, amount))
+}
+
+func (m *timeoutManager) Transfer(
+	ctx context.Context,
+	uid int64,
+	src, dst uint32,
+	amount float32,
+) error {
+	ctx, cancel := m.context(ctx, "transfer")
+	defer cancel()
+	return expired(ctx, m.Manager.Transfer(ctx, uid, src, dst, amount))
+}
+
+func (m *timeoutManager) Deposit(
+	ctx context.Context,
+	peer string,
+	uid int64,
+	account uint32,
+	amount float32,
+) error {
+	ctx, cancel := m.context(ctx, "deposit")
+	defer cancel()
+	return expired(ctx, m.Manager.Deposit(ctx, peer, uid, account, amount))
+}
+
+func (m *timeoutManager) Acquire(
+	ctx context.Context,
+	uid int64,
+	account uint32,
+	dst uint32,
+	amount float32,
+) error {
+	ctx, cancel := m.context(ctx, "acquire")
+	defer cancel()
+	return expired(ctx, m.Manager.Acquire(ctx, uid, account, dst, amount))
+}
+
+func (m *timeoutManager) Commit(
+	ctx context.Context,
+	uid int64,
+	account uint32,
+) error {
+	ctx, cancel := m.context(ctx, "commit")
+	defer cancel()
+	return expired(ctx, m.Manager.Commit(ctx, uid, account))
+}
+
+func (m *timeoutManager) Rollback(
+	ctx context.Context,
+	uid int64,
+	account uint32,
+) error {
+	ctx, cancel := m.context(ctx, "rollback")
+	defer cancel()
+	return expired(ctx, m.Manager.Rollback(ctx, uid, account))
+}
+
+func (m *timeoutManager) Reverse(
+	ctx context.Context,
+	reversal *domain.Reversal,
+) error {
+	ctx, cancel := m.context(ctx, "reverse")
+	defer cancel()
+	return expired(ctx, m.Manager.Reverse(ctx, reversal))
+}
+
+func (m *timeoutManager) Balance(
+	ctx context.Context,
+	account uint32,
+) (float32, error) {
+	ctx, cancel := m.context(ctx, "balance")
+	defer cancel()
+	amount, err := m.Manager.Balance(ctx, account)
+	return amount, expired(ctx, err)
+}
+
+func (m *timeoutManager) History(
+	ctx context.Context,
+	account uint32,
+	offset, limit int,
+) ([]*domain.HistoryRecord, error) {
+	ctx, cancel := m.context(ctx, "history")
+	defer cancel()
+	records, err := m.Manager.History(ctx, account, offset, limit)
+	return records, expired(ctx, err)
+}
+
+// Derive context of the operation.
+// Deadline of the parent context (set by the caller) is kept, if it is earlier.
+func (m *timeoutManager) context(
+	ctx context.Context,
+	operation string,
+) (context.Context, context.CancelFunc) {
+	return operationContext(ctx, m.options(), operation)
+}
+
+func operationContext(
+	ctx context.Context,
+	options domain.TimeoutOptions,
+	operation string,
+) (context.Context, context.CancelFunc) {
+	timeout := options.Get(operation)
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// Escrow with limited duration of the operations
+type timeoutEscrow struct {
+	banker.Escrow
+	options func() domain.TimeoutOptions
+}
+
+func (m *timeoutEscrow) Open(
+	ctx context.Context,
+	uid int64,
+	id int64,
+	account uint32,
+	amount float32,
+) error {
+	ctx, cancel := operationContext(ctx, m.options(), "escrow.open")
+	defer cancel()
+	return expired(ctx, m.Escrow.Open(ctx, uid, id, account, amount))
+}
+
+func (m *timeoutEscrow) Release(
+	ctx context.Context,
+	uid int64,
+	id int64,
+	account uint32,
+	payouts []domain.Payout,
+) error {
+	ctx, cancel := operationContext(ctx, m.options(), "escrow.release")
+	defer cancel()
+	return expired(ctx, m.Escrow.Release(ctx, uid, id, account, payouts))
+}
+
+func (m *timeoutEscrow) Refund(
+	ctx context.Context,
+	uid int64,
+	id int64,
+	account uint32,
+	amount float32,
+) error {
+	ctx, cancel := operationContext(ctx, m.options(), "escrow.refund")
+	defer cancel()
+	return expired(ctx, m.Escrow.Refund(ctx, uid, id, account, amount))
+}
+
+func (m *timeoutEscrow) Get(
+	ctx context.Context,
+	id int64,
+	account uint32,
+) (*domain.Escrow, []*domain.EscrowRecord, error) {
+	ctx, cancel := operationContext(ctx, m.options(), "escrow.get")
+	defer cancel()
+	escrow, records, err := m.Escrow.Get(ctx, id, account)
+	return escrow, records, expired(ctx, err)
+}
+
+// Replace error of the interrupted operation by the error of the context.
+// Database driver reports interruption by various errors (e.g. broken connection).
+func expired(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// Decorate banker with timeouts of the operations
+func NewTimeoutManager(
+	manager banker.Manager,
+	options domain.TimeoutOptions,
+) banker.Manager {
+	return NewReloadableTimeoutManager(
+		manager,
+		func() domain.TimeoutOptions { return options },
+	)
+}
+
+// Decorate escrow with timeouts of the operations, that are got for each operation
+func NewReloadableTimeoutEscrow(
+	escrow banker.Escrow,
+	options func() domain.TimeoutOptions,
+) banker.Escrow {
+	return &timeoutEscrow{
+		Escrow:  escrow,
+		options: options,
+	}
+}
+
+// Decorate banker with timeouts of the operations, that are got for each operation
+func NewReloadableTimeoutManager(
+	manager banker.Manager,
+	options func() domain.TimeoutOptions,
+) banker.Manager {
+	return &timeoutManager{
+		Manager: manager,
+		options: options,
+	}
+}
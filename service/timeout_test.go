@@ -0,0 +1,75 @@
+package service
+
+import (
+	"billing/domain"
+	"context"
+	"database/sql/driver"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type slowManager struct {
+	managerMock
+	deadlines []time.Duration
+}
+
+func (m *slowManager) Transfer(ctx context.Context, uid int64, src, dst uint32, amount float32) error {
+	deadline, _ := ctx.Deadline()
+	m.deadlines = append(m.deadlines, time.Until(deadline).Round(100*time.Millisecond))
+	<-ctx.Done()
+	return driver.ErrBadConn
+}
+
+func TestTimeoutManager(t *testing.T) {
+	type Src struct {
+		options  domain.TimeoutOptions
+		deadline time.Duration
+	}
+
+	type Test struct {
+		src Src
+		dst time.Duration
+	}
+
+	tests := map[string]Test{
+		"Default timeout must be used": {
+			src: Src{
+				options: domain.TimeoutOptions{Default: 200},
+			},
+			dst: 200 * time.Millisecond,
+		},
+		"Timeout of the operation must override default": {
+			src: Src{
+				options: domain.TimeoutOptions{
+					Default:    200,
+					Operations: map[string]int{"transfer": 100},
+				},
+			},
+			dst: 100 * time.Millisecond,
+		},
+		"Earlier deadline of the caller must be kept": {
+			src: Src{
+				options:  domain.TimeoutOptions{Default: 60000},
+				deadline: 100 * time.Millisecond,
+			},
+			dst: 100 * time.Millisecond,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			if test.src.deadline != 0 {
+				ctx, cancel = context.WithTimeout(context.Background(), test.src.deadline)
+			}
+			defer cancel()
+
+			slow := &slowManager{}
+			manager := NewTimeoutManager(slow, test.src.options)
+			err := manager.Transfer(ctx, 1, 1, 2, 10)
+			assert.Equal(t, context.DeadlineExceeded, err)
+			assert.Equal(t, []time.Duration{test.dst}, slow.deadlines)
+		})
+	}
+}
@@ -0,0 +1,394 @@
+package service
+
+import (
+	"billing/domain"
+	"billing/manager/banker"
+	"context"
+	"fmt"
+	"github.com/adverax/echo/database/sql"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Register W3C trace context propagator and provider of the tracer with the configured exporter.
+// Returns function, that flushes the spans and stops the exporter.
+// Without exporter spans are not recorded, but the trace context of the caller is still propagated.
+func SetupTracing(
+	ctx context.Context,
+	options domain.TracingOptions,
+) (func(ctx context.Context) error, error) {
+	otel.SetTextMapPropagator(
+		propagation.NewCompositeTextMapPropagator(
+			propagation.TraceContext{},
+			propagation.Baggage{},
+		),
+	)
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch options.Exporter {
+	case "", "none":
+		return func(ctx context.Context) error { return nil }, nil
+	case "otlp":
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(options.Endpoint)}
+		if options.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		exporter, err = otlptracegrpc.New(ctx, opts...)
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", options.Exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(
+			resource.NewWithAttributes(
+				semconv.SchemaURL,
+				semconv.ServiceNameKey.String(options.ServiceName),
+			),
+		),
+		sdktrace.WithSampler(
+			sdktrace.ParentBased(sdktrace.TraceIDRatioBased(options.SampleRatio)),
+		),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// Start span of the message handling.
+// Span is the child of the trace context, extracted from the message headers.
+func startMessageSpan(
+	ctx context.Context,
+	msg Message,
+) (context.Context, trace.Span) {
+	if hc, ok := msg.(HeaderCarrier); ok {
+		ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(hc.Headers()))
+	}
+
+	return domain.Tracer().Start(
+		ctx,
+		msg.Subject(),
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.operation", "process"),
+			attribute.String("messaging.destination.name", msg.Subject()),
+		),
+	)
+}
+
+// Finish span of the message handling with the result of the operation
+func endMessageSpan(
+	span trace.Span,
+	response Response,
+) {
+	span.SetAttributes(attribute.Int("billing.status", int(response.Status)))
+	if response.Error != nil {
+		span.SetStatus(codes.Error, response.Error.Code)
+	}
+	span.End()
+}
+
+// Get headers with the trace context
+func traceHeaders(ctx context.Context) map[string]string {
+	headers := make(propagation.MapCarrier)
+	otel.GetTextMapPropagator().Inject(ctx, headers)
+	return headers
+}
+
+// Banker, that traces operations
+type tracingManager struct {
+	banker.Manager
+}
+
+func (m *tracingManager) Credit(
+	ctx context.Context,
+	uid int64,
+	account uint32,
+	amount float32,
+) error {
+	ctx, span := startOperationSpan(ctx, "credit",
+		attribute.Int64("billing.uid", uid),
+		attribute.Int64("billing.account", int64(account)),
+		attribute.Float64("billing.amount", float64(amount)),
+	)
+	err := m.Manager.Credit(ctx, uid, account, amount)
+	domain.EndSpan(span, err)
+	return err
+}
+
+func (m *tracingManager) Debit(
+	ctx context.Context,
+	uid int64,
+	account uint32,
+	amount float32,
+) error {
+	ctx, span := startOperationSpan(ctx, "debit",
+		attribute.Int64("billing.uid", uid),
+		attribute.Int64("billing.account", int64(account)),
+		attribute.Float64("billing.amount", float64(amount)),
+	)
+	err := m.Manager.Debit(ctx, uid, account, amount)
+	domain.EndSpan(span, err)
+	return err
+}
+
+func (m *tracingManager) Transfer(
+	ctx context.Context,
+	uid int64,
+	src, dst uint32,
+	amount float32,
+) error {
+	ctx, span := startOperationSpan(ctx, "transfer",
+		attribute.Int64("billing.uid", uid),
+		attribute.Int64("billing.src", int64(src)),
+		attribute.Int64("billing.dst", int64(dst)),
+		attribute.Float64("billing.amount", float64(amount)),
+	)
+	err := m.Manager.Transfer(ctx, uid, src, dst, amount)
+	domain.EndSpan(span, err)
+	return err
+}
+
+func (m *tracingManager) Deposit(
+	ctx context.Context,
+	peer string,
+	uid int64,
+	account uint32,
+	amount float32,
+) error {
+	ctx, span := startOperationSpan(ctx, "deposit",
+		attribute.String("billing.peer", peer),
+		attribute.Int64("billing.uid", uid),
+		attribute.Int64("billing.account", int64(account)),
+		attribute.Float64("billing.amount", float64(amount)),
+	)
+	err := m.Manager.Deposit(ctx, peer, uid, account, amount)
+	domain.EndSpan(span, err)
+	return err
+}
+
+func (m *tracingManager) Acquire(
+	ctx context.Context,
+	uid int64,
+	account uint32,
+	dst uint32,
+	amount float32,
+) error {
+	ctx, span := startOperationSpan(ctx, "acquire",
+		attribute.Int64("billing.uid", uid),
+		attribute.Int64("billing.account", int64(account)),
+		attribute.Float64("billing.amount", float64(amount)),
+	)
+	if dst != 0 {
+		span.SetAttributes(attribute.Int64("billing.dst", int64(dst)))
+	}
+	err := m.Manager.Acquire(ctx, uid, account, dst, amount)
+	domain.EndSpan(span, err)
+	return err
+}
+
+func (m *tracingManager) Commit(
+	ctx context.Context,
+	uid int64,
+	account uint32,
+) error {
+	ctx, span := startOperationSpan(ctx, "commit",
+		attribute.Int64("billing.uid", uid),
+		attribute.Int64("billing.account", int64(account)),
+	)
+	err := m.Manager.Commit(ctx, uid, account)
+	domain.EndSpan(span, err)
+	return err
+}
+
+func (m *tracingManager) Rollback(
+	ctx context.Context,
+	uid int64,
+	account uint32,
+) error {
+	ctx, span := startOperationSpan(ctx, "rollback",
+		attribute.Int64("billing.uid", uid),
+		attribute.Int64("billing.account", int64(account)),
+	)
+	err := m.Manager.Rollback(ctx, uid, account)
+	domain.EndSpan(span, err)
+	return err
+}
+
+func (m *tracingManager) Reverse(
+	ctx context.Context,
+	reversal *domain.Reversal,
+) error {
+	ctx, span := startOperationSpan(ctx, "reverse",
+		attribute.Int64("billing.uid", reversal.Uid),
+		attribute.Int64("billing.original", reversal.Original),
+		attribute.Int64("billing.account", int64(reversal.Account)),
+		attribute.Float64("billing.amount", float64(reversal.Amount)),
+	)
+	if reversal.Dst != 0 {
+		span.SetAttributes(attribute.Int64("billing.dst", int64(reversal.Dst)))
+	}
+	err := m.Manager.Reverse(ctx, reversal)
+	domain.EndSpan(span, err)
+	return err
+}
+
+func (m *tracingManager) Balance(
+	ctx context.Context,
+	account uint32,
+) (float32, error) {
+	ctx, span := startOperationSpan(ctx, "balance",
+		attribute.Int64("billing.account", int64(account)),
+	)
+	amount, err := m.Manager.Balance(ctx, account)
+	domain.EndSpan(span, err)
+	return amount, err
+}
+
+func (m *tracingManager) History(
+	ctx context.Context,
+	account uint32,
+	offset, limit int,
+) ([]*domain.HistoryRecord, error) {
+	ctx, span := startOperationSpan(ctx, "history",
+		attribute.Int64("billing.account", int64(account)),
+	)
+	records, err := m.Manager.History(ctx, account, offset, limit)
+	domain.EndSpan(span, err)
+	return records, err
+}
+
+func startOperationSpan(
+	ctx context.Context,
+	operation string,
+	attributes ...attribute.KeyValue,
+) (context.Context, trace.Span) {
+	return domain.Tracer().Start(
+		ctx,
+		"banker."+operation,
+		trace.WithAttributes(attributes...),
+	)
+}
+
+// Decorate banker with spans of the operations
+func NewTracingManager(manager banker.Manager) banker.Manager {
+	return &tracingManager{
+		Manager: manager,
+	}
+}
+
+// Escrow, that traces operations
+type tracingEscrow struct {
+	banker.Escrow
+}
+
+func (m *tracingEscrow) Open(
+	ctx context.Context,
+	uid int64,
+	id int64,
+	account uint32,
+	amount float32,
+) error {
+	ctx, span := startOperationSpan(ctx, "escrow.open",
+		attribute.Int64("billing.uid", uid),
+		attribute.Int64("billing.escrow", id),
+		attribute.Int64("billing.account", int64(account)),
+		attribute.Float64("billing.amount", float64(amount)),
+	)
+	err := m.Escrow.Open(ctx, uid, id, account, amount)
+	domain.EndSpan(span, err)
+	return err
+}
+
+func (m *tracingEscrow) Release(
+	ctx context.Context,
+	uid int64,
+	id int64,
+	account uint32,
+	payouts []domain.Payout,
+) error {
+	ctx, span := startOperationSpan(ctx, "escrow.release",
+		attribute.Int64("billing.uid", uid),
+		attribute.Int64("billing.escrow", id),
+		attribute.Int64("billing.account", int64(account)),
+		attribute.Int("billing.payouts", len(payouts)),
+	)
+	err := m.Escrow.Release(ctx, uid, id, account, payouts)
+	domain.EndSpan(span, err)
+	return err
+}
+
+func (m *tracingEscrow) Refund(
+	ctx context.Context,
+	uid int64,
+	id int64,
+	account uint32,
+	amount float32,
+) error {
+	ctx, span := startOperationSpan(ctx, "escrow.refund",
+		attribute.Int64("billing.uid", uid),
+		attribute.Int64("billing.escrow", id),
+		attribute.Int64("billing.account", int64(account)),
+		attribute.Float64("billing.amount", float64(amount)),
+	)
+	err := m.Escrow.Refund(ctx, uid, id, account, amount)
+	domain.EndSpan(span, err)
+	return err
+}
+
+func (m *tracingEscrow) Get(
+	ctx context.Context,
+	id int64,
+	account uint32,
+) (*domain.Escrow, []*domain.EscrowRecord, error) {
+	ctx, span := startOperationSpan(ctx, "escrow.get",
+		attribute.Int64("billing.escrow", id),
+		attribute.Int64("billing.account", int64(account)),
+	)
+	escrow, records, err := m.Escrow.Get(ctx, id, account)
+	domain.EndSpan(span, err)
+	return escrow, records, err
+}
+
+// Decorate escrow with spans of the operations
+func NewTracingEscrow(escrow banker.Escrow) banker.Escrow {
+	return &tracingEscrow{
+		Escrow: escrow,
+	}
+}
+
+// Repository, that traces transactions
+type tracingRepository struct {
+	sql.Repository
+}
+
+func (r *tracingRepository) Transaction(
+	ctx context.Context,
+	action func(ctx context.Context) error,
+) error {
+	ctx, span := domain.Tracer().Start(ctx, "db.transaction")
+	err := r.Repository.Transaction(ctx, action)
+	domain.EndSpan(span, err)
+	return err
+}
+
+// Decorate repository with spans of the transactions
+func NewTracingRepository(repository sql.Repository) sql.Repository {
+	return &tracingRepository{
+		Repository: repository,
+	}
+}
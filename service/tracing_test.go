@@ -0,0 +1,101 @@
+package service
+
+import (
+	"billing/domain"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+	"log/slog"
+	"testing"
+)
+
+type headerMessage struct {
+	subject      string
+	data         []byte
+	headers      map[string]string
+	reply        []byte
+	replyHeaders map[string]string
+}
+
+func (m *headerMessage) Subject() string {
+	return m.subject
+}
+
+func (m *headerMessage) Data() []byte {
+	return m.data
+}
+
+func (m *headerMessage) Reply(data []byte) error {
+	return m.ReplyWithHeaders(data, nil)
+}
+
+func (m *headerMessage) Headers() map[string]string {
+	return m.headers
+}
+
+func (m *headerMessage) ReplyWithHeaders(data []byte, headers map[string]string) error {
+	m.reply = data
+	m.replyHeaders = headers
+	return nil
+}
+
+// Register recording tracer provider until the end of the test
+func setUpTracing(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	t.Cleanup(func() {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+	})
+
+	return recorder
+}
+
+func TestServer_HandleTrace(t *testing.T) {
+	const traceId = "4bf92f3577b34da6a3ce929d0e0e4736"
+	const parentId = "00f067aa0ba902b7"
+
+	recorder := setUpTracing(t)
+
+	manager := NewTracingManager(&managerMock{err: domain.ErrNoMoney})
+	s := &server{
+		manager: manager,
+		logger:  slog.New(slog.DiscardHandler),
+	}
+	msg := &headerMessage{
+		subject: "bank.credit",
+		data:    []byte(`{"uid":1,"account":7,"amount":10}`),
+		headers: map[string]string{
+			"traceparent": "00-" + traceId + "-" + parentId + "-01",
+		},
+	}
+
+	s.handle(context.Background(), endpoints(manager)["credit"], msg)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	operation, handler := spans[0], spans[1]
+
+	assert.Equal(t, "bank.credit", handler.Name())
+	assert.Equal(t, traceId, handler.SpanContext().TraceID().String())
+	assert.Equal(t, parentId, handler.Parent().SpanID().String())
+	assert.Equal(t, codes.Error, handler.Status().Code)
+
+	assert.Equal(t, "banker.credit", operation.Name())
+	assert.Equal(t, traceId, operation.SpanContext().TraceID().String())
+	assert.Equal(t, handler.SpanContext().SpanID(), operation.Parent().SpanID())
+	assert.Equal(t, codes.Error, operation.Status().Code)
+
+	assert.Contains(t, string(msg.reply), `"Status":3`)
+	assert.Equal(t, map[string]string{
+		"traceparent": "00-" + traceId + "-" + handler.SpanContext().SpanID().String() + "-01",
+	}, msg.replyHeaders)
+}
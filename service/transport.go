@@ -0,0 +1,67 @@
+package service
+
+import "context"
+
+// Incoming message of the transport
+type Message interface {
+	// Subject (topic, queue) of the message
+	Subject() string
+	// Raw payload of the message
+	Data() []byte
+	// Send response to the requester. Does nothing, if response is not expected.
+	Reply(data []byte) error
+}
+
+type Handler func(msg Message)
+
+// Transport connects service with a message broker
+type Transport interface {
+	// Subscribe handler to the subject.
+	// Messages are distributed among all subscribers of the same queue group.
+	Subscribe(subject, queue string, handler Handler) error
+	// Publish message to the subject
+	Publish(subject string, data []byte) error
+	// Stop receiving new messages and wait until handlers of the received messages return.
+	// Connection remains open, so replies can be sent until the transport is closed.
+	Drain() error
+	// Close connection with broker
+	Close() error
+}
+
+// Message, that can be returned to the broker for redelivery
+type Requeuer interface {
+	// Return message to the broker instead of reply.
+	// Returns false, if the message can not be redelivered anymore.
+	Requeue() bool
+}
+
+// Transport, that buffers received messages
+type PendingCounter interface {
+	// Count of the received messages, that are not handled yet, by subject
+	Pending() (map[string]int, error)
+}
+
+// Message, that must be completely handled before the handler returns,
+// because the broker confirms it right after the handler (e.g. offset of the Kafka message).
+type Synchronous interface {
+	Synchronous()
+}
+
+// Message with headers (e.g. trace context of the caller)
+type HeaderCarrier interface {
+	// Headers of the message
+	Headers() map[string]string
+	// Send response with headers to the requester
+	ReplyWithHeaders(data []byte, headers map[string]string) error
+}
+
+// Transport, that can publish messages with headers
+type HeaderPublisher interface {
+	PublishWithHeaders(subject string, data []byte, headers map[string]string) error
+}
+
+// Transport, that reports state of the connection with the broker
+type Checker interface {
+	// Get error, if the broker is not available
+	Check(ctx context.Context) error
+}